@@ -14,6 +14,7 @@ import (
 	"github.com/kingcobra2468/cot/internal/router/worker"
 	"github.com/kingcobra2468/cot/internal/router/worker/crypto"
 	"github.com/kingcobra2468/cot/internal/router/worker/gvoice"
+	"github.com/kingcobra2468/cot/internal/router/worker/parser"
 	"github.com/kingcobra2468/cot/internal/service"
 	"github.com/spf13/viper"
 )
@@ -45,9 +46,17 @@ func init() {
 // in the config file.
 func parseServices() (*config.Services, error) {
 	var c config.Services
-	err := viper.Unmarshal(&c)
+	if err := viper.Unmarshal(&c); err != nil {
+		return nil, err
+	}
+	// an unset separator disables splitting texts into multiple commands
+	if viper.IsSet("command_separator") {
+		if err := parser.CheckSeparator(c.CommandSeparator); err != nil {
+			return nil, err
+		}
+	}
 
-	return &c, err
+	return &c, nil
 }
 
 // parseGVMS retrieves GVMS connection configuration.
@@ -119,10 +128,12 @@ func main() {
 	}
 
 	serviceCache := service.NewCache()
-	serviceCache.Add(services...)
+	if err := serviceCache.Add(services...); err != nil {
+		glog.Fatalln(err)
+	}
 
 	textWorkers := worker.GenerateGVoiceWorkers(sc, gvc)
-	commandExecutor := router.NewEventLoop(5, len(*textWorkers), time.Second*10, serviceCache)
+	commandExecutor := router.NewEventLoop(5, len(*textWorkers), time.Second*10, sc.BatchReplies, serviceCache)
 
 	for _, w := range *textWorkers {
 		commandExecutor.AddWorker(w)
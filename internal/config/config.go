@@ -5,48 +5,171 @@ package config
 // Services contains configuration on each of the services and the client
 // numbers authorized to use it. Also contains the GVoice number
 // bindings that the client numbers send messages to. The ability to
-// see whether text encryption is enabled is also accessible.
+// see whether text encryption is enabled is also accessible, as well as
+// how multiple commands within a single text are separated and replied to.
 type Services struct {
-	Services       []*Service `mapstructure:"services"`
-	GVoiceNumber   string     `mapstructure:"gvoice_number"`
-	TextEncryption bool       `mapstructure:"text_encryption"`
+	Services         []*Service `mapstructure:"services"`
+	GVoiceNumber     string     `mapstructure:"gvoice_number"`
+	TextEncryption   bool       `mapstructure:"text_encryption"`
+	CommandSeparator string     `mapstructure:"command_separator"`
+	BatchReplies     bool       `mapstructure:"batch_replies"`
 }
 
 // Service contains configuration on the service name (also used as the command name)
-// as well as the service base URI. Also contains a list of client numbers authorized
-// to use this service.
+// as well as the service base URI and how to authenticate with it. Also contains a list
+// of client numbers authorized to use this service.
 type Service struct {
-	Name          string     `mapstructure:"name"`
-	BaseURI       string     `mapstructure:"base_uri"`
-	ClientNumbers []string   `mapstructure:"client_numbers"`
-	Commands      []*Command `mapstructure:"commands"`
+	Name          string            `mapstructure:"name"`
+	Description   string            `mapstructure:"description"`
+	Aliases       []string          `mapstructure:"aliases"`
+	BaseURI       string            `mapstructure:"base_uri"`
+	Headers       map[string]string `mapstructure:"headers"`
+	Auth          *Auth             `mapstructure:"auth"`
+	TLS           *TLS              `mapstructure:"tls"`
+	Transport     *Transport        `mapstructure:"transport"`
+	Breaker       *Breaker          `mapstructure:"circuit_breaker"`
+	ClientNumbers []string          `mapstructure:"client_numbers"`
+	Commands      []*Command        `mapstructure:"commands"`
 }
 
 // Command contains the signature for each of the subcommands. This includes the pattern
 // that determines if the command exists from the user input, as well as various metadata
 // in regards to how to send that command to a given client service.
 type Command struct {
-	Pattern  string   `mapstructure:"pattern"`
-	Method   string   `mapstructure:"method"`
-	Endpoint string   `mapstructure:"endpoint"`
-	Args     *[]Arg   `mapstructure:"args"`
-	Response Response `mapstructure:"response"`
+	Pattern      string            `mapstructure:"pattern"`
+	Description  string            `mapstructure:"description"`
+	Aliases      []string          `mapstructure:"aliases"`
+	Method       string            `mapstructure:"method"`
+	Endpoint     string            `mapstructure:"endpoint"`
+	BodyType     string            `mapstructure:"body_type"`
+	BodyTemplate string            `mapstructure:"body_template"`
+	ContentType  string            `mapstructure:"content_type"`
+	Headers      map[string]string `mapstructure:"headers"`
+	Timeout      string            `mapstructure:"timeout"`
+	Retry        *Retry            `mapstructure:"retry"`
+	Args         *[]Arg            `mapstructure:"args"`
+	Response     Response          `mapstructure:"response"`
 }
 
 // Arg represents argument config for a given command of a given client service.
 type Arg struct {
 	TypeInfo     `mapstructure:",squash"`
 	Index        int           `mapstructure:"index"`
+	Name         string        `mapstructure:"name"`
+	Group        string        `mapstructure:"group"`
 	Type         string        `mapstructure:"type"`
 	CompressRest bool          `mapstructure:"compress_rest"`
+	JoinRest     bool          `mapstructure:"join_rest"`
 	Filter       []interface{} `mapstructure:"filter"`
+	Required     *bool         `mapstructure:"required"`
+	Default      interface{}   `mapstructure:"default"`
+	Validate     []Rule        `mapstructure:"validate"`
+	Values       []interface{} `mapstructure:"values"`
+	Layouts      []string      `mapstructure:"layouts"`
+	Timezone     string        `mapstructure:"timezone"`
+	Format       string        `mapstructure:"format"`
+}
+
+// Retry contains the configuration on how a failed command request is retried. The delay
+// between attempts starts at the backoff and doubles after each attempt.
+type Retry struct {
+	MaxAttempts   int    `mapstructure:"max_attempts"`
+	Backoff       string `mapstructure:"backoff"`
+	MaxBackoff    string `mapstructure:"max_backoff"`
+	StatusCodes   []int  `mapstructure:"status_codes"`
+	NonIdempotent bool   `mapstructure:"non_idempotent"`
+}
+
+// Rule represents a set of validation constraints for a given argument. A custom
+// error message can be provided for when any of the constraints are not satisfied.
+type Rule struct {
+	Regex           string        `mapstructure:"regex"`
+	Min             *float64      `mapstructure:"min"`
+	Max             *float64      `mapstructure:"max"`
+	MinLength       *int          `mapstructure:"min_length"`
+	MaxLength       *int          `mapstructure:"max_length"`
+	Enum            []interface{} `mapstructure:"enum"`
+	Deny            []interface{} `mapstructure:"deny"`
+	CaseInsensitive bool          `mapstructure:"case_insensitive"`
+	Message         string        `mapstructure:"message"`
+}
+
+// Auth contains the configuration on how to authenticate with a client service. The secret
+// is either the bearer token, the basic auth password or the API key based on the type.
+type Auth struct {
+	Type     string `mapstructure:"type"`
+	Header   string `mapstructure:"header"`
+	Username string `mapstructure:"username"`
+	Secret   Secret `mapstructure:"secret"`
+}
+
+// Secret contains the location from which a secret is read, which is either an environment
+// variable or a file.
+type Secret struct {
+	Env  string `mapstructure:"env"`
+	File string `mapstructure:"file"`
+}
+
+// TLS contains the configuration on how to establish TLS connections with a client service.
+// This includes the CA bundle that the client service is verified against and the client
+// certificate that cot presents for mutual TLS.
+type TLS struct {
+	CAFile     string `mapstructure:"ca_file"`
+	CertFile   string `mapstructure:"cert_file"`
+	KeyFile    string `mapstructure:"key_file"`
+	ServerName string `mapstructure:"server_name"`
+	MinVersion string `mapstructure:"min_version"`
+}
+
+// Transport contains the configuration on how the connections to a client service are
+// pooled, as well as the proxy that they are established through.
+type Transport struct {
+	MaxIdleConns int    `mapstructure:"max_idle_conns"`
+	MaxConns     int    `mapstructure:"max_conns"`
+	IdleTimeout  string `mapstructure:"idle_timeout"`
+	Proxy        string `mapstructure:"proxy"`
+}
+
+// Breaker contains the configuration on when requests to a failing client service are
+// stopped and when they are attempted again.
+type Breaker struct {
+	FailureThreshold int    `mapstructure:"failure_threshold"`
+	CoolOff          string `mapstructure:"cool_off"`
 }
 
 // Response contains the configuration of the response signature of a given command.
 type Response struct {
-	Type    string   `mapstructure:"type"`
-	Success TypeInfo `mapstructure:"success"`
-	Error   TypeInfo `mapstructure:"error"`
+	Type      string           `mapstructure:"type"`
+	Success   Extract          `mapstructure:"success"`
+	Error     Extract          `mapstructure:"error"`
+	Template  string           `mapstructure:"template"`
+	Responses map[string]Reply `mapstructure:"responses"`
+}
+
+// Reply represents how the reply is produced from a response with a given status code or
+// range of status codes. The reply is either a static message, rendered from a template or
+// extracted from the response.
+type Reply struct {
+	Extract  `mapstructure:",squash"`
+	Template string `mapstructure:"template"`
+	Message  string `mapstructure:"message"`
+}
+
+// Extract represents how the reply is extracted from a response. Either the value at the
+// path or a list of labelled fields is extracted, which are then formatted based on their
+// datatype.
+type Extract struct {
+	TypeInfo  `mapstructure:",squash"`
+	Fields    []Field `mapstructure:"fields"`
+	Precision *int    `mapstructure:"precision"`
+	TrueWord  string  `mapstructure:"true_word"`
+	FalseWord string  `mapstructure:"false_word"`
+}
+
+// Field represents a labelled value that is extracted from a response.
+type Field struct {
+	TypeInfo `mapstructure:",squash"`
+	Label    string `mapstructure:"label"`
 }
 
 // TypeInfo represents type info metadata for a given argument or response type.
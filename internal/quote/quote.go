@@ -0,0 +1,101 @@
+// quote implements the shell-like quoting rules of input commands, which are shared by
+// the tokenizing of texts into args and the unquoting of the matches of command patterns.
+package quote
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Scanner tracks the quotes and escapes of a text as it is scanned one rune at a time.
+// Double quotes group text and allow for backslash escapes of quotes and backslashes
+// within them. Single quotes group text literally, but only open at the start of a word
+// when a closing single quote follows later in the text, so that apostrophes within words
+// (e.g. "don't") and at the start of words (e.g. "'twas") are left untouched. Outside of
+// quotes, a backslash escapes the character that follows it.
+type Scanner struct {
+	quote   rune
+	escaped bool
+	// whether a word is being scanned
+	inWord bool
+}
+
+// Literal checks whether the next rune is neither quoted nor escaped, in which case the
+// caller decides whether it separates words (e.g. whitespace).
+func (s *Scanner) Literal() bool {
+	return s.quote == 0 && !s.escaped
+}
+
+// Next scans a rune of a word, followed by the rest of the text, and writes the text that
+// it contributes to the word. Quotes and the backslashes of escapes contribute nothing.
+func (s *Scanner) Next(r rune, rest string, word *strings.Builder) {
+	inWord := s.inWord
+	s.inWord = true
+
+	switch {
+	case s.escaped:
+		// within double quotes only quotes and backslashes can be escaped
+		if s.quote == '"' && r != '"' && r != '\\' {
+			word.WriteRune('\\')
+		}
+		word.WriteRune(r)
+		s.escaped = false
+	case s.quote == '\'':
+		if r == '\'' {
+			s.quote = 0
+		} else {
+			word.WriteRune(r)
+		}
+	case s.quote == '"':
+		switch r {
+		case '"':
+			s.quote = 0
+		case '\\':
+			s.escaped = true
+		default:
+			word.WriteRune(r)
+		}
+	case r == '\\':
+		s.escaped = true
+	case r == '"' || (r == '\'' && !inWord && strings.ContainsRune(rest, '\'')):
+		s.quote = r
+	default:
+		word.WriteRune(r)
+	}
+}
+
+// EndWord marks the end of the current word.
+func (s *Scanner) EndWord() {
+	s.inWord = false
+}
+
+// Unbalanced checks whether a quote was opened but never closed.
+func (s *Scanner) Unbalanced() bool {
+	return s.quote != 0
+}
+
+// Dangling checks whether the text ended with a lone backslash.
+func (s *Scanner) Dangling() bool {
+	return s.escaped
+}
+
+// Unquote removes the quotes and escapes from a text, while keeping its whitespace, so
+// that "\"model s\"" is unquoted as "model s". A trailing lone backslash is kept as is.
+func Unquote(text string) string {
+	var b strings.Builder
+	s := Scanner{}
+	for i, r := range text {
+		if s.Literal() && unicode.IsSpace(r) {
+			b.WriteRune(r)
+			s.EndWord()
+			continue
+		}
+		s.Next(r, text[i+utf8.RuneLen(r):], &b)
+	}
+	if s.Dangling() {
+		b.WriteRune('\\')
+	}
+
+	return b.String()
+}
@@ -3,6 +3,8 @@
 package router
 
 import (
+	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -26,13 +28,15 @@ type EventLoop struct {
 	maxWorkers int
 	cache      *service.Cache
 	coolDown   time.Duration
+	// whether to send the replies to commands from the same text as a single message
+	batchReplies bool
 }
 
 // NewEventLoop creates a new instance of EventLoop.
-func NewEventLoop(maxReceivers, maxWorkers int, coolDown time.Duration, cache *service.Cache) *EventLoop {
+func NewEventLoop(maxReceivers, maxWorkers int, coolDown time.Duration, batchReplies bool, cache *service.Cache) *EventLoop {
 	queue := make(chan Worker, maxReceivers)
 
-	return &EventLoop{queue: queue, maxWorkers: maxWorkers, coolDown: coolDown, cache: cache}
+	return &EventLoop{queue: queue, maxWorkers: maxWorkers, coolDown: coolDown, batchReplies: batchReplies, cache: cache}
 }
 
 // AddWorker adds a new worker to the worker pool.
@@ -76,47 +80,135 @@ func (el *EventLoop) loop(done chan struct{}) *sync.WaitGroup {
 	return &workers
 }
 
-// process handles incoming commands.
+// process handles incoming commands. Replies to the commands that were sent within
+// the same text are either sent together as a single message or individually, depending
+// on whether reply batching is enabled.
 func (el *EventLoop) process(w Worker) {
-	for _, command := range *(w.Fetch()) {
-		// check for "ping" requests
-		if strings.EqualFold(command.Name, "ping") {
-			glog.Infoln("executed \"ping\" request")
-			w.Send("pong")
-			continue
+	commands := *(w.Fetch())
+	replies := []string{}
+	for i, command := range commands {
+		if msg, ok := el.execute(w, &command); ok {
+			if el.batchReplies {
+				replies = append(replies, msg)
+			} else {
+				w.Send(msg)
+			}
 		}
-		// ignore "pong" command relay
-		if w.LoopBack() && strings.EqualFold(command.Name, "pong") {
-			continue
+
+		// send the batched replies once the last command of a text has been executed
+		lastInBatch := i == len(commands)-1 || commands[i+1].Batch != command.Batch
+		if el.batchReplies && lastInBatch && len(replies) > 0 {
+			w.Send(strings.Join(replies, "\n"))
+			replies = replies[:0]
 		}
+	}
+}
 
-		recipient := w.Recipient()
-		// check if the command request is authorized given the client number
-		// that initiated it
-		if !service.ClientAuthorized(command.Name, recipient) {
-			glog.Warningf("%s attempted to run command \"%s\" while unauthorized to do so", recipient, command.Name)
-			continue
+// execute handles a single incoming command and returns the reply for it, if any.
+func (el *EventLoop) execute(w Worker, command *service.UserInput) (string, bool) {
+	// check for "ping" requests
+	if strings.EqualFold(command.Name, "ping") {
+		glog.Infoln("executed \"ping\" request")
+		return "pong", true
+	}
+	// ignore "pong" command relay
+	if w.LoopBack() && strings.EqualFold(command.Name, "pong") {
+		return "", false
+	}
+
+	recipient := w.Recipient()
+	// check for "help" requests
+	if strings.EqualFold(command.Name, "help") {
+		glog.Infof("executed \"help\" request with args \"%v\"", command.Args)
+		return el.help(recipient, command.Args), true
+	}
+	el.cache.Resolve(command)
+	// check if the command request is authorized given the client number
+	// that initiated it
+	if !service.ClientAuthorized(command.Name, recipient) {
+		glog.Warningf("%s attempted to run command \"%s\" while unauthorized to do so", recipient, command.Name)
+		// avoid relaying replies back and forth with the gvoice number itself
+		if w.LoopBack() {
+			return "", false
 		}
+		return el.unknownCommand(recipient, command.Name), true
+	}
 
-		clientPool, err := el.cache.Get(command.Name)
-		if err != nil {
-			glog.Warningf("invalid command \"%s\" found", command.Name)
-			continue
+	client, err := el.cache.Get(command.Name)
+	if err != nil {
+		glog.Warningf("invalid command \"%s\" found", command.Name)
+		return el.unknownCommand(recipient, command.Name), true
+	}
+
+	glog.Infof("executed \"%s\" with args \"%v\"", command.Name, command.Args)
+	command.ClientNumber = recipient
+	msg, err := client.Execute(command)
+	if err != nil {
+		msg = err.Error()
+	}
+
+	return msg, true
+}
+
+// help renders the list of commands that a client number is authorized to run. If a
+// command is specified, then the usage of that command is rendered instead.
+func (el *EventLoop) help(recipient string, args []string) string {
+	if len(args) > 0 {
+		ui := service.UserInput{Name: strings.ToLower(args[0])}
+		el.cache.Resolve(&ui)
+		name := ui.Name
+		s, ok := el.service(name)
+		if !ok || !service.ClientAuthorized(name, recipient) {
+			return el.unknownCommand(recipient, args[0])
 		}
-		client, ok := clientPool.Get().(*service.Service)
-		if !ok {
-			glog.Errorf("unable to fetch client from %s's service pool", command.Name)
-			w.Send("internal error, try later")
+
+		return s.Usage()
+	}
+
+	names := el.cache.Services()
+	sort.Strings(names)
+	lines := []string{}
+	for _, name := range names {
+		if !service.ClientAuthorized(name, recipient) {
 			continue
 		}
-		glog.Infof("executed \"%s\" with args \"%v\"", command.Name, command.Args)
-		msg, err := client.Execute(&command)
-		if err != nil {
-			msg = err.Error()
+		if s, ok := el.service(name); ok {
+			lines = append(lines, s.Summary())
+		}
+	}
+	if len(lines) == 0 {
+		return "no commands available"
+	}
+
+	return "commands:\n" + strings.Join(lines, "\n") + "\nsend \"help [command]\" for usage"
+}
+
+// unknownCommand renders the reply for a command that either does not exist or that the
+// client number is not authorized to run. Both cases are treated the same to avoid revealing
+// which commands exist. A suggestion is made from the commands that the client number is
+// authorized to run in case of a typo.
+func (el *EventLoop) unknownCommand(recipient, name string) string {
+	names := []string{}
+	for _, n := range el.cache.Services() {
+		if service.ClientAuthorized(n, recipient) {
+			names = append(names, n)
 		}
+	}
+	sort.Strings(names)
 
-		w.Send(msg)
+	if suggestion, ok := service.Suggest(name, names); ok {
+		return fmt.Sprintf("unknown command '%s', did you mean '%s'?", name, suggestion)
+	}
+
+	return fmt.Sprintf("unknown command '%s', send 'help' for a list of commands", name)
+}
 
-		clientPool.Put(client)
+// service fetches a service from the cache.
+func (el *EventLoop) service(name string) (*service.Service, bool) {
+	s, err := el.cache.Get(name)
+	if err != nil {
+		return nil, false
 	}
+
+	return s, true
 }
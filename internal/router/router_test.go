@@ -8,6 +8,7 @@ import (
 	"github.com/kingcobra2468/cot/internal/router/mocks"
 	"github.com/kingcobra2468/cot/internal/service"
 	"github.com/kingcobra2468/cot/internal/testutil"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -34,7 +35,7 @@ func TestProcess(t *testing.T) {
 	mockWorker.On("Recipient").Return(recipientNumber)
 	mockWorker.On("Send", mock.Anything).Return(nil)
 
-	el := NewEventLoop(2, 2, coolDown, &cache)
+	el := NewEventLoop(2, 2, coolDown, false, &cache)
 	el.AddWorker(mockWorker)
 
 	done := make(chan struct{})
@@ -61,7 +62,7 @@ func TestProcess_stress(t *testing.T) {
 	cache.Add(*s)
 
 	mockWorkers := make([]*mocks.Worker, 8)
-	el := NewEventLoop(8, 6, coolDown, &cache)
+	el := NewEventLoop(8, 6, coolDown, false, &cache)
 
 	for i := 0; i < 8; i++ {
 		mockWorker := mocks.NewWorker(t)
@@ -105,7 +106,7 @@ func TestProcess_ping(t *testing.T) {
 	mockWorker.EXPECT().Send("pong").Return(nil)
 	mockWorker.On("Send", mock.Anything).Return(nil)
 
-	el := NewEventLoop(2, 2, coolDown, &cache)
+	el := NewEventLoop(2, 2, coolDown, false, &cache)
 	el.AddWorker(mockWorker)
 
 	done := make(chan struct{})
@@ -133,7 +134,7 @@ func TestProcess_pong(t *testing.T) {
 	mockWorker.EXPECT().Fetch().Return(&[]service.UserInput{{Name: "pong", Raw: "pong"}})
 	mockWorker.On("LoopBack").Return(true)
 
-	el := NewEventLoop(2, 2, coolDown, &cache)
+	el := NewEventLoop(2, 2, coolDown, false, &cache)
 	el.AddWorker(mockWorker)
 
 	done := make(chan struct{})
@@ -149,3 +150,46 @@ func TestProcess_pong(t *testing.T) {
 
 	mockWorker.AssertExpectations(t)
 }
+
+func TestHelp(t *testing.T) {
+	server := testutil.NewStubServer(t)
+	s := testutil.NewFakeService(server, commandName, recipientNumber)
+	s.Description = "test service"
+	hidden := testutil.NewFakeService(server, "hidden", "2")
+
+	cache := *service.NewCache()
+	cache.Add(*s, *hidden)
+	el := NewEventLoop(2, 2, coolDown, false, &cache)
+
+	assert.Equal(t, "commands:\ntest - test service\nsend \"help [command]\" for usage", el.help(recipientNumber, []string{}))
+	assert.Equal(t, "test - test service", el.help(recipientNumber, []string{"Test"}))
+	assert.Equal(t, "unknown command 'hidden', send 'help' for a list of commands", el.help(recipientNumber, []string{"hidden"}))
+}
+
+func TestUnknownCommand(t *testing.T) {
+	server := testutil.NewStubServer(t)
+	s := testutil.NewFakeService(server, commandName, recipientNumber)
+	hidden := testutil.NewFakeService(server, "tent", "2")
+
+	cache := *service.NewCache()
+	cache.Add(*s, *hidden)
+	el := NewEventLoop(2, 2, coolDown, false, &cache)
+
+	assert.Equal(t, "unknown command 'tset', did you mean 'test'?", el.unknownCommand(recipientNumber, "tset"))
+	assert.Equal(t, "unknown command 'tent', did you mean 'test'?", el.unknownCommand(recipientNumber, "tent"))
+	assert.Equal(t, "unknown command 'car', send 'help' for a list of commands", el.unknownCommand(recipientNumber, "car"))
+}
+
+func TestProcess_batch(t *testing.T) {
+	cache := *service.NewCache()
+
+	mockWorker := mocks.NewWorker(t)
+	mockWorker.EXPECT().Fetch().Return(&[]service.UserInput{{Name: "ping", Raw: "ping", Batch: 1}, {Name: "ping", Raw: "ping", Batch: 1}, {Name: "ping", Raw: "ping", Batch: 2}})
+	mockWorker.EXPECT().Send("pong\npong").Return(nil).Once()
+	mockWorker.EXPECT().Send("pong").Return(nil).Once()
+
+	el := NewEventLoop(2, 2, coolDown, true, &cache)
+	el.process(mockWorker)
+
+	mockWorker.AssertExpectations(t)
+}
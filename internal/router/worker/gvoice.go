@@ -35,6 +35,8 @@ type GVoiceWorker struct {
 	latestTextTime uint64
 	encryption     bool
 	gvmsClient     gvoice.GVoiceClient
+	// separates multiple commands within a single text
+	separator string
 }
 
 // minNumMessages is the minimum number of messages to fetch on the first iteration
@@ -44,7 +46,7 @@ const minNumMessages uint64 = 5
 // GenerateGVoiceWorkers creates a list of Worker instances from the configuration file. This
 // will also add each of the client numbers to the whitelist in the process.
 func GenerateGVoiceWorkers(c *config.Services, gvc gvoice.GVoiceClient) *[]*GVoiceWorker {
-	workers := []*GVoiceWorker{NewGVoiceWorker(Link{GVoiceNumber: c.GVoiceNumber, ClientNumber: c.GVoiceNumber}, false, gvc)}
+	workers := []*GVoiceWorker{NewGVoiceWorker(Link{GVoiceNumber: c.GVoiceNumber, ClientNumber: c.GVoiceNumber}, false, c.CommandSeparator, gvc)}
 	for _, s := range c.Services {
 		for _, cn := range s.ClientNumbers {
 			// check if worker exists (to avoid duplicate workers)
@@ -53,7 +55,8 @@ func GenerateGVoiceWorkers(c *config.Services, gvc gvoice.GVoiceClient) *[]*GVoi
 				continue
 			}
 
-			workers = append(workers, NewGVoiceWorker(Link{GVoiceNumber: c.GVoiceNumber, ClientNumber: cn}, c.TextEncryption, gvc))
+			w := NewGVoiceWorker(Link{GVoiceNumber: c.GVoiceNumber, ClientNumber: cn}, c.TextEncryption, c.CommandSeparator, gvc)
+			workers = append(workers, w)
 			service.AddClient(s.Name, cn)
 			glog.Infof("created new gvoice worker for %s", cn)
 		}
@@ -62,13 +65,14 @@ func GenerateGVoiceWorkers(c *config.Services, gvc gvoice.GVoiceClient) *[]*GVoi
 	return &workers
 }
 
-// NewGVoiceWorker creates a new instance of GVoice source worker.
-func NewGVoiceWorker(link Link, encryption bool, c gvoice.GVoiceClient) *GVoiceWorker {
+// NewGVoiceWorker creates a new instance of GVoice source worker. Texts are split into
+// multiple commands by the separator unless it is empty.
+func NewGVoiceWorker(link Link, encryption bool, separator string, c gvoice.GVoiceClient) *GVoiceWorker {
 	// get the current time to prevent old commands (those which existed prior to start of cot)
 	// from being executed
 	currentTime := uint64(time.Now().Unix()) * 1000
 
-	return &GVoiceWorker{link: link, encryption: encryption,
+	return &GVoiceWorker{link: link, encryption: encryption, separator: separator,
 		latestTextTime: currentTime, gvmsClient: c}
 }
 
@@ -92,7 +96,18 @@ func (gw *GVoiceWorker) Fetch() *[]service.UserInput {
 			}
 		}
 
-		if command, err := parser.Parse(msg); err == nil {
+		parsed, err := parser.ParseAll(msg, gw.separator)
+		if err != nil {
+			// let the client number know that the text was malformed, as opposed
+			// to being empty
+			if errors.Is(err, parser.ErrUnbalancedQuotes) || errors.Is(err, parser.ErrDanglingEscape) {
+				gw.Send(err.Error())
+			}
+			continue
+		}
+		// mark the commands as originating from the same text
+		for _, command := range parsed {
+			command.Batch = text.Timestamp
 			commands = append(commands, *command)
 		}
 	}
@@ -208,10 +223,15 @@ func encode(message string) string {
 	message = strings.ReplaceAll(message, "\\\"", "\"")
 	// encode all quotes
 	message = strings.ReplaceAll(message, "\"", "\\\"")
-	// remove all newlines as they cannot exist when sending messages with gvms
-	message = strings.ReplaceAll(message, "\n", "")
+	// join lines with a delimiter as newlines cannot exist when sending messages with gvms
+	lines := []string{}
+	for _, line := range strings.Split(message, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
 
-	return message
+	return strings.Join(lines, " | ")
 }
 
 func (gw *GVoiceWorker) LoopBack() bool {
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/kingcobra2468/cot/internal/config"
 	"github.com/kingcobra2468/cot/internal/router/worker/gvoice"
 	"github.com/kingcobra2468/cot/internal/router/worker/gvoice/mocks"
 	"github.com/kingcobra2468/cot/internal/service"
@@ -29,20 +30,22 @@ var pastTime uint64 = unixTime() - hour
 
 func TestFetch(t *testing.T) {
 	var tests = []struct {
-		name  string
-		input []*gvoice.MessageNode
-		want  *[]service.UserInput
+		name      string
+		separator string
+		input     []*gvoice.MessageNode
+		want      *[]service.UserInput
 	}{
-		{"0 Messages", []*gvoice.MessageNode{}, &[]service.UserInput{}},
-		{"1 Message", []*gvoice.MessageNode{{Timestamp: lo.ToPtr(futureTime), MessageContents: lo.ToPtr("test"), Source: lo.ToPtr(true)}}, &[]service.UserInput{{Name: "test", Args: []string{}, Raw: "test"}}},
-		{"2 Messages", []*gvoice.MessageNode{{Timestamp: lo.ToPtr(futureTime), MessageContents: lo.ToPtr("test a"), Source: lo.ToPtr(true)}, {Timestamp: lo.ToPtr(pastTime), MessageContents: lo.ToPtr("test1"), Source: lo.ToPtr(true)}}, &[]service.UserInput{{Name: "test", Args: []string{"a"}, Raw: "test a"}}},
+		{"0 Messages", "", []*gvoice.MessageNode{}, &[]service.UserInput{}},
+		{"1 Message", "", []*gvoice.MessageNode{{Timestamp: lo.ToPtr(futureTime), MessageContents: lo.ToPtr("test"), Source: lo.ToPtr(true)}}, &[]service.UserInput{{Name: "test", Args: []string{}, Raw: "test", Batch: futureTime}}},
+		{"2 Messages", "", []*gvoice.MessageNode{{Timestamp: lo.ToPtr(futureTime), MessageContents: lo.ToPtr("test a"), Source: lo.ToPtr(true)}, {Timestamp: lo.ToPtr(pastTime), MessageContents: lo.ToPtr("test1"), Source: lo.ToPtr(true)}}, &[]service.UserInput{{Name: "test", Args: []string{"a"}, Raw: "test a", Batch: futureTime}}},
+		{"2 Commands", ";", []*gvoice.MessageNode{{Timestamp: lo.ToPtr(futureTime), MessageContents: lo.ToPtr("test a; test \"b;c\";"), Source: lo.ToPtr(true)}}, &[]service.UserInput{{Name: "test", Args: []string{"a"}, Raw: "test a", Batch: futureTime}, {Name: "test", Args: []string{"b;c"}, Raw: "test \"b;c\"", Batch: futureTime}}},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockGVoiceClient := mocks.NewGVoiceClient(t)
 			mockGVoiceClient.EXPECT().GetContactHistory(mock.Anything, mock.Anything, mock.Anything).Return(&gvoice.FetchContactHistoryResponse{Success: lo.ToPtr(true), Messages: tt.input}, nil)
-			w := NewGVoiceWorker(Link{"", ""}, false, mockGVoiceClient)
+			w := NewGVoiceWorker(Link{"", ""}, false, tt.separator, mockGVoiceClient)
 
 			ui := w.Fetch()
 			assert.Equal(t, len(*ui), len(*tt.want))
@@ -52,6 +55,19 @@ func TestFetch(t *testing.T) {
 	}
 }
 
+func TestGenerateGVoiceWorkers_loopBack(t *testing.T) {
+	mockGVoiceClient := mocks.NewGVoiceClient(t)
+	mockGVoiceClient.EXPECT().GetContactHistory(mock.Anything, mock.Anything, mock.Anything).Return(&gvoice.FetchContactHistoryResponse{Success: lo.ToPtr(true),
+		Messages: []*gvoice.MessageNode{{Timestamp: lo.ToPtr(futureTime), MessageContents: lo.ToPtr("ping; ping"), Source: lo.ToPtr(true)}}}, nil)
+	workers := GenerateGVoiceWorkers(&config.Services{GVoiceNumber: "+15555555555", CommandSeparator: ";"}, mockGVoiceClient)
+	assert.Len(t, *workers, 1)
+	w := (*workers)[0]
+	assert.True(t, w.LoopBack())
+
+	ui := w.Fetch()
+	assert.Equal(t, []service.UserInput{{Name: "ping", Args: []string{}, Raw: "ping", Batch: futureTime}, {Name: "ping", Args: []string{}, Raw: "ping", Batch: futureTime}}, *ui)
+}
+
 func TestUnprocessedTexts(t *testing.T) {
 	var tests = []struct {
 		name  string
@@ -67,7 +83,7 @@ func TestUnprocessedTexts(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockGVoiceClient := mocks.NewGVoiceClient(t)
 			mockGVoiceClient.EXPECT().GetContactHistory(mock.Anything, mock.Anything, mock.Anything).Return(&gvoice.FetchContactHistoryResponse{Success: lo.ToPtr(true), Messages: tt.input}, nil)
-			w := NewGVoiceWorker(Link{"", ""}, false, mockGVoiceClient)
+			w := NewGVoiceWorker(Link{"", ""}, false, "", mockGVoiceClient)
 
 			texts, _ := w.unprocessedTexts()
 			assert.Equal(t, len(*texts), len(*tt.want))
@@ -92,7 +108,7 @@ func TestNewTexts(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockGVoiceClient := mocks.NewGVoiceClient(t)
 			mockGVoiceClient.EXPECT().GetContactHistory(mock.Anything, mock.Anything, mock.Anything).Return(&gvoice.FetchContactHistoryResponse{Success: lo.ToPtr(true), Messages: tt.input}, nil)
-			w := NewGVoiceWorker(Link{"", ""}, false, mockGVoiceClient)
+			w := NewGVoiceWorker(Link{"", ""}, false, "", mockGVoiceClient)
 
 			texts, _ := w.newTexts(10)
 			assert.Equal(t, len(*texts), len(*tt.want))
@@ -152,3 +168,21 @@ func TestOldestNewText(t *testing.T) {
 		})
 	}
 }
+
+func TestEncode(t *testing.T) {
+	var tests = []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"Single line", "pong", "pong"},
+		{"Multiple lines", "car - manage cars\n  car price <0:str>\n\n", "car - manage cars | car price <0:str>"},
+		{"Quotes", `say "hi" and \"bye\"`, `say \"hi\" and \"bye\"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, encode(tt.input))
+		})
+	}
+}
@@ -4,21 +4,141 @@ package parser
 
 import (
 	"errors"
+	"fmt"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/kingcobra2468/cot/internal/quote"
 	"github.com/kingcobra2468/cot/internal/service"
 )
 
 var (
 	errUnparsableCommand = errors.New("unable to parse command")
+	// ErrUnbalancedQuotes is returned when a text contains a quote that is never closed.
+	ErrUnbalancedQuotes = errors.New("unable to parse command due to unbalanced quotes")
+	// ErrDanglingEscape is returned when a text ends with a lone backslash.
+	ErrDanglingEscape = errors.New("unable to parse command due to a trailing backslash")
 )
 
+// smartQuotes maps the typographic quotes that phone keyboards tend to insert
+// into their plain ASCII counterparts.
+var smartQuotes = strings.NewReplacer(
+	"“", "\"", "”", "\"", "„", "\"", "‟", "\"",
+	"‘", "'", "’", "'", "‚", "'", "‛", "'",
+)
+
+// CheckSeparator validates a command separator. Separators must not contain quotes or
+// backslashes, as those are part of the quoting rules, nor whitespace, as that already
+// splits the tokens of a command. A newline is the exception, as it splits a text into
+// lines of commands.
+func CheckSeparator(separator string) error {
+	if separator == "" {
+		return errors.New("invalid command separator: must not be empty")
+	}
+	if separator == "\n" {
+		return nil
+	}
+	if strings.ContainsAny(smartQuotes.Replace(separator), "\"'\\") {
+		return fmt.Errorf("invalid command separator \"%s\": must not contain quotes or backslashes", separator)
+	}
+	if strings.IndexFunc(separator, unicode.IsSpace) != -1 {
+		return fmt.Errorf("invalid command separator \"%s\": must not contain whitespace", separator)
+	}
+
+	return nil
+}
+
 // Parse parses the input text into an instance of a Command.
 func Parse(text string) (*service.UserInput, error) {
-	tokens := strings.Fields(text)
-	if len(tokens) == 0 {
+	commands, err := ParseAll(text, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return commands[0], nil
+}
+
+// ParseAll parses the input text into one or more instances of a Command, where each
+// command is separated by the separator. Separators within quotes or that are escaped
+// do not split the text. Empty commands (e.g. due to a trailing separator) are ignored.
+// If the separator is empty, then the whole text is parsed as a single command.
+func ParseAll(text, separator string) ([]*service.UserInput, error) {
+	text = smartQuotes.Replace(text)
+	segments, err := tokenize(text, separator)
+	if err != nil {
+		return nil, err
+	}
+
+	commands := []*service.UserInput{}
+	for _, seg := range segments {
+		if len(seg.tokens) == 0 {
+			continue
+		}
+		commands = append(commands, &service.UserInput{Name: strings.ToLower(seg.tokens[0]), Args: seg.tokens[1:], Raw: seg.raw})
+	}
+	if len(commands) == 0 {
 		return nil, errUnparsableCommand
 	}
 
-	return &service.UserInput{Name: strings.ToLower(tokens[0]), Args: tokens[1:], Raw: text}, nil
+	return commands, nil
+}
+
+// segment is the portion of a text between separators.
+type segment struct {
+	tokens []string
+	raw    string
+}
+
+// tokenize splits the text into segments by the separator and each segment into tokens
+// in a shell-like manner, following the quoting rules of quote.Scanner. Whitespace and
+// separators split the text unless they are quoted or escaped.
+func tokenize(text, separator string) ([]segment, error) {
+	segments := []segment{}
+	tokens := []string{}
+	var token strings.Builder
+	// whether a token is being built, as a quoted empty string is still a token
+	inToken := false
+	scanner := quote.Scanner{}
+	// byte offset of the start of the current segment and of the end of the last separator
+	start, skip := 0, 0
+
+	endToken := func() {
+		if inToken {
+			tokens = append(tokens, token.String())
+			token.Reset()
+			inToken = false
+		}
+		scanner.EndWord()
+	}
+
+	for i, r := range text {
+		if i < skip {
+			continue
+		}
+
+		switch {
+		case scanner.Literal() && separator != "" && strings.HasPrefix(text[i:], separator):
+			endToken()
+			segments = append(segments, segment{tokens: tokens, raw: strings.TrimSpace(text[start:i])})
+			tokens = []string{}
+			start, skip = i+len(separator), i+len(separator)
+		case scanner.Literal() && unicode.IsSpace(r):
+			endToken()
+		default:
+			scanner.Next(r, text[i+utf8.RuneLen(r):], &token)
+			inToken = true
+		}
+	}
+
+	if scanner.Unbalanced() {
+		return nil, ErrUnbalancedQuotes
+	}
+	if scanner.Dangling() {
+		return nil, ErrDanglingEscape
+	}
+	endToken()
+	segments = append(segments, segment{tokens: tokens, raw: strings.TrimSpace(text[start:])})
+
+	return segments, nil
 }
@@ -0,0 +1,85 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/kingcobra2468/cot/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	var tests = []struct {
+		name  string
+		input string
+		want  *service.UserInput
+	}{
+		{"No args", "Test", &service.UserInput{Name: "test", Args: []string{}, Raw: "Test"}},
+		{"Plain args", "note add milk  eggs", &service.UserInput{Name: "note", Args: []string{"add", "milk", "eggs"}, Raw: "note add milk  eggs"}},
+		{"Double quotes", `note add "buy milk and eggs"`, &service.UserInput{Name: "note", Args: []string{"add", "buy milk and eggs"}, Raw: `note add "buy milk and eggs"`}},
+		{"Single quotes", `note add 'say "hi"'`, &service.UserInput{Name: "note", Args: []string{"add", `say "hi"`}, Raw: `note add 'say "hi"'`}},
+		{"Smart quotes", "note add “buy milk”", &service.UserInput{Name: "note", Args: []string{"add", "buy milk"}, Raw: `note add "buy milk"`}},
+		{"Apostrophe", "note add don’t forget", &service.UserInput{Name: "note", Args: []string{"add", "don't", "forget"}, Raw: "note add don't forget"}},
+		{"Leading apostrophe", "note add 'twas fine", &service.UserInput{Name: "note", Args: []string{"add", "'twas", "fine"}, Raw: "note add 'twas fine"}},
+		{"Escapes", `note add buy\ milk "say \"hi\" \n"`, &service.UserInput{Name: "note", Args: []string{"add", "buy milk", `say "hi" \n`}, Raw: `note add buy\ milk "say \"hi\" \n"`}},
+		{"Empty quotes", `note add ""`, &service.UserInput{Name: "note", Args: []string{"add", ""}, Raw: `note add ""`}},
+		{"Mid-token quotes", `note --text="a b"`, &service.UserInput{Name: "note", Args: []string{"--text=a b"}, Raw: `note --text="a b"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ui, err := Parse(tt.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, ui)
+		})
+	}
+}
+
+func TestParse_invalid(t *testing.T) {
+	var tests = []struct {
+		name  string
+		input string
+		want  error
+	}{
+		{"Empty", "  ", errUnparsableCommand},
+		{"Unbalanced double quote", `note add "buy milk`, ErrUnbalancedQuotes},
+		{"Trailing backslash", `note add milk\`, ErrDanglingEscape},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.input)
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+func TestParseAll(t *testing.T) {
+	var tests = []struct {
+		name      string
+		input     string
+		separator string
+		want      []*service.UserInput
+	}{
+		{"No separator", "car remove a; car remove b", "", []*service.UserInput{{Name: "car", Args: []string{"remove", "a;", "car", "remove", "b"}, Raw: "car remove a; car remove b"}}},
+		{"Semicolon", "car remove a; car remove b;", ";", []*service.UserInput{{Name: "car", Args: []string{"remove", "a"}, Raw: "car remove a"}, {Name: "car", Args: []string{"remove", "b"}, Raw: "car remove b"}}},
+		{"Newline", "car remove a\ncar remove 'b\nc'", "\n", []*service.UserInput{{Name: "car", Args: []string{"remove", "a"}, Raw: "car remove a"}, {Name: "car", Args: []string{"remove", "b\nc"}, Raw: "car remove 'b\nc'"}}},
+		{"Escaped separator", `note add a\;b`, ";", []*service.UserInput{{Name: "note", Args: []string{"add", "a;b"}, Raw: `note add a\;b`}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commands, err := ParseAll(tt.input, tt.separator)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, commands)
+		})
+	}
+}
+
+func TestCheckSeparator(t *testing.T) {
+	for _, separator := range []string{";", "\n", "&&"} {
+		assert.NoError(t, CheckSeparator(separator), separator)
+	}
+	for _, separator := range []string{"", " ", "; ", "\t", `"`, "'", "’", `\`} {
+		assert.Error(t, CheckSeparator(separator), separator)
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+)
+
+// checkCommandAliases assigns a keyword to each of the commands of a service and validates
+// that the command aliases neither collide with each other nor with the keywords of other
+// commands. Commands with aliases must have a pattern whose keyword is the first word after
+// the service name, as that is the word that aliases are resolved at.
+func (s *Service) checkCommandAliases() error {
+	keywords := make(map[string]*Command)
+	for _, p := range s.Patterns {
+		c := s.Meta[p]
+		if c.Keyword = s.patternKeyword(p); c.Keyword != "" {
+			keywords[c.Keyword] = c
+		}
+	}
+
+	aliases := make(map[string]*Command)
+	for _, p := range s.Patterns {
+		c := s.Meta[p]
+		if len(c.Aliases) > 0 && c.Keyword == "" {
+			return fmt.Errorf("aliases of pattern \"%s\" in service \"%s\" require the pattern to contain a literal word", p, s.Name)
+		}
+		if len(c.Aliases) > 0 && !s.keywordLeads(p, c.Keyword) {
+			return fmt.Errorf("aliases of pattern \"%s\" in service \"%s\" require \"%s\" to be the first word after the service name", p, s.Name, c.Keyword)
+		}
+		for _, alias := range c.Aliases {
+			if other, exists := keywords[alias]; exists && other != c {
+				return fmt.Errorf("alias \"%s\" in service \"%s\" collides with a subcommand", alias, s.Name)
+			}
+			if other, exists := aliases[alias]; exists && other != c {
+				return fmt.Errorf("alias \"%s\" is repeated in service \"%s\"", alias, s.Name)
+			}
+			aliases[alias] = c
+		}
+	}
+
+	return nil
+}
+
+// keywordLeads checks whether the keyword of a command pattern is the first word after the
+// service name. Besides the service name, only anchors and a leading wildcard (e.g. the
+// ".*" of ".*remove.*") may come before the keyword.
+func (s Service) keywordLeads(pattern, keyword string) bool {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return false
+	}
+	subs := []*syntax.Regexp{re}
+	if re.Op == syntax.OpConcat {
+		subs = re.Sub
+	}
+
+	leading := true
+	for _, sub := range subs {
+		switch {
+		case sub.Op == syntax.OpBeginText || sub.Op == syntax.OpBeginLine:
+			continue
+		case leading && sub.Op == syntax.OpStar && (sub.Sub[0].Op == syntax.OpAnyChar || sub.Sub[0].Op == syntax.OpAnyCharNotNL):
+		case sub.Op == syntax.OpLiteral:
+			for _, word := range strings.FieldsFunc(string(sub.Rune), isNotWordRune) {
+				if strings.EqualFold(word, keyword) {
+					return true
+				}
+				if !strings.EqualFold(word, s.Name) && !containsFold(s.Aliases, word) {
+					return false
+				}
+			}
+		default:
+			return false
+		}
+		leading = false
+	}
+
+	return false
+}
+
+// resolveAlias rewrites the first arg of the input command into the keyword of a command
+// if it is one of the aliases of that command. The raw input command is rewritten likewise
+// so that the command pattern can match it.
+func (s Service) resolveAlias(ui *UserInput) {
+	if len(ui.Args) == 0 {
+		return
+	}
+
+	for _, p := range s.Patterns {
+		c, ok := s.Meta[p]
+		if !ok || !containsFold(c.Aliases, ui.Args[0]) {
+			continue
+		}
+
+		ui.Raw = replaceToken(ui.Raw, 1, c.Keyword)
+		ui.Args[0] = c.Keyword
+		return
+	}
+}
+
+// replaceToken replaces the whitespace-separated token at the given position of a text.
+// The text is left as is if no such token exists.
+func replaceToken(text string, pos int, token string) string {
+	start, end, count := -1, -1, 0
+	inToken := false
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			if inToken && count == pos {
+				end = i
+				break
+			}
+			if inToken {
+				count++
+			}
+			inToken = false
+			continue
+		}
+		if !inToken && count == pos {
+			start = i
+		}
+		inToken = true
+	}
+
+	if start == -1 {
+		return text
+	}
+	if end == -1 {
+		end = len(text)
+	}
+
+	return text[:start] + token + text[end:]
+}
+
+// containsFold checks whether a list contains a given value, ignoring case.
+func containsFold(list []string, val string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, val) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lowerAll converts each of the values of a list into lowercase.
+func lowerAll(list []string) []string {
+	lowered := make([]string, 0, len(list))
+	for _, item := range list {
+		lowered = append(lowered, strings.ToLower(item))
+	}
+
+	return lowered
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+)
+
+// argInput contains the args of an input command once they have been split into
+// positional args, named args and the named capture groups of the command pattern.
+type argInput struct {
+	// Args that are referenced by their positional index.
+	positional []string
+	// Mapping between the name of a named arg and its raw value.
+	named map[string]string
+	// Mapping between a named capture group of the command pattern and its match.
+	groups map[string]string
+}
+
+// splitArgs separates the named args of the input command from its positional args.
+// Tokens of the form "--key=value" are always treated as named args, while tokens of
+// the form "key:value" are only treated as named args when "key" is the name of one
+// of the command's args. A "--key" token without a value is shorthand for "--key=true".
+// Unknown "--key" tokens are an error, unless the command has a compressed arg, in which
+// case they are positional args as they may be part of free text.
+// The positional index of the remaining args is computed with named args excluded.
+func (sc Command) splitArgs(ui *UserInput, groups map[string]string) (*argInput, error) {
+	names := sc.argNames()
+	freeText := sc.compressesRest()
+	if groups == nil {
+		groups = make(map[string]string)
+	}
+	in := argInput{positional: []string{}, named: make(map[string]string), groups: groups}
+
+	for _, token := range ui.Args {
+		var name, val string
+		if strings.HasPrefix(token, "--") && len(token) > 2 {
+			var found bool
+			name, val, found = strings.Cut(token[2:], "=")
+			if !found {
+				val = "true"
+			}
+			name = strings.ToLower(name)
+			if _, exists := names[name]; !exists {
+				if freeText {
+					in.positional = append(in.positional, token)
+					continue
+				}
+				return nil, fmt.Errorf("unknown named arg \"%s\"", name)
+			}
+		} else if key, v, found := strings.Cut(token, ":"); found {
+			if _, exists := names[strings.ToLower(key)]; !exists {
+				in.positional = append(in.positional, token)
+				continue
+			}
+			name, val = strings.ToLower(key), v
+		} else {
+			in.positional = append(in.positional, token)
+			continue
+		}
+
+		if _, exists := in.named[name]; exists {
+			return nil, fmt.Errorf("named arg \"%s\" was given more than once", name)
+		}
+		in.named[name] = val
+	}
+
+	return &in, nil
+}
+
+// argNames fetches the set of names of all of the named args of a command.
+func (sc Command) argNames() map[string]struct{} {
+	names := make(map[string]struct{})
+	if sc.Args == nil {
+		return names
+	}
+	for _, group := range *sc.Args {
+		for _, arg := range group {
+			if arg.Name != "" {
+				names[arg.Name] = struct{}{}
+			}
+		}
+	}
+
+	return names
+}
+
+// compressesRest checks whether a positional arg of a command consumes the rest of the
+// positional args.
+func (sc Command) compressesRest() bool {
+	if sc.Args == nil {
+		return false
+	}
+	for _, group := range *sc.Args {
+		for _, arg := range group {
+			if arg.Compress && arg.positional() {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// value fetches the raw value of an arg from the input command if it was provided.
+func (in *argInput) value(a *Arg) (string, bool) {
+	if a.Group != "" {
+		val, ok := in.groups[a.Group]
+		return val, ok
+	}
+	if a.Name != "" {
+		val, ok := in.named[a.Name]
+		return val, ok
+	}
+	if a.Index < 0 || a.Index >= len(in.positional) {
+		return "", false
+	}
+
+	return in.positional[a.Index], true
+}
+
+// lookup fetches the raw values of an arg from the input command, falling back to the
+// default value of the arg if it was not provided. A compressed arg consumes the rest of
+// the positional args, either as separate values or joined by spaces into a single value.
+// Optional args without a default value that were not provided have no values.
+func (in *argInput) lookup(a *Arg) ([]string, error) {
+	if a.Compress && a.positional() && a.Index >= 0 && a.Index < len(in.positional) {
+		rest := in.positional[a.Index:]
+		if a.Join {
+			return []string{strings.Join(rest, " ")}, nil
+		}
+		return rest, nil
+	}
+	if val, ok := in.value(a); ok {
+		return []string{val}, nil
+	}
+	if a.Default != nil {
+		return []string{*a.Default}, nil
+	}
+	if !a.Required {
+		return []string{}, nil
+	}
+
+	return nil, fmt.Errorf("unable to parse input command due to missing %s", a.label())
+}
+
+// label describes how an arg is referenced from the input command.
+func (a Arg) label() string {
+	if a.Group != "" {
+		return fmt.Sprintf("arg for group \"%s\"", a.Group)
+	}
+	if a.Name != "" {
+		return fmt.Sprintf("arg \"%s\"", a.Name)
+	}
+
+	return fmt.Sprintf("arg at index %d", a.Index)
+}
+
+// key fetches the key that an arg is referenced by within endpoint placeholders and
+// templates, which is its path, or otherwise its name or group.
+func (a Arg) key() string {
+	if a.Path != "" {
+		return a.Path
+	}
+	if a.Name != "" {
+		return a.Name
+	}
+
+	return a.Group
+}
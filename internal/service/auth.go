@@ -0,0 +1,124 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/kingcobra2468/cot/internal/config"
+)
+
+// Client service authentication type.
+type AuthType int8
+
+// AuthType lists the different ways in which cot can authenticate with a client service.
+const (
+	// InvalidAuth describes an auth type that was specified for a given service but does
+	// not exist within cot.
+	InvalidAuth AuthType = iota
+	// BearerAuth describes authentication via a bearer token within the Authorization header.
+	BearerAuth
+	// BasicAuth describes authentication via a username and password within the
+	// Authorization header.
+	BasicAuth
+	// ApiKeyAuth describes authentication via an API key within a custom header.
+	ApiKeyAuth
+)
+
+// defaultApiKeyHeader is the header that an API key is sent in when none is specified.
+const defaultApiKeyHeader = "X-API-Key"
+
+// Auth describes how to authenticate the requests to a given client service.
+type Auth struct {
+	Type AuthType
+	// Header that the API key is sent in.
+	Header   string
+	Username string
+	// Bearer token, basic auth password or API key based on the auth type.
+	Secret string
+}
+
+// generateAuth parses and validates the auth of a service from the configuration file.
+// The secret is read from its environment variable or file.
+func generateAuth(authInfo *config.Auth) (*Auth, error) {
+	if authInfo == nil {
+		return nil, nil
+	}
+
+	t, err := parseAuthType(authInfo.Type)
+	if err != nil {
+		return nil, err
+	}
+	if t == BasicAuth && authInfo.Username == "" {
+		return nil, errors.New("basic auth requires a username")
+	}
+	secret, err := readSecret(authInfo.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	auth := Auth{Type: t, Username: authInfo.Username, Secret: secret}
+	if t == ApiKeyAuth {
+		auth.Header = defaultApiKeyHeader
+		if authInfo.Header != "" {
+			auth.Header = authInfo.Header
+		}
+	}
+
+	return &auth, nil
+}
+
+// readSecret reads a secret from either an environment variable or a file. A trailing
+// newline within a secret file is ignored.
+func readSecret(secretInfo config.Secret) (string, error) {
+	switch {
+	case secretInfo.Env != "" && secretInfo.File != "":
+		return "", errors.New("auth secret cannot be read from both an env var and a file")
+	case secretInfo.Env != "":
+		secret, ok := os.LookupEnv(secretInfo.Env)
+		if !ok || secret == "" {
+			return "", fmt.Errorf("auth secret env var \"%s\" is not set", secretInfo.Env)
+		}
+		return secret, nil
+	case secretInfo.File != "":
+		b, err := os.ReadFile(secretInfo.File)
+		if err != nil {
+			return "", fmt.Errorf("unable to read auth secret file: %w", err)
+		}
+		secret := strings.TrimRight(string(b), "\r\n")
+		if secret == "" {
+			return "", fmt.Errorf("auth secret file \"%s\" is empty", secretInfo.File)
+		}
+		return secret, nil
+	default:
+		return "", errors.New("auth requires a secret from either an env var or a file")
+	}
+}
+
+// parseAuthType processes the raw auth type into one of the supported auth types.
+func parseAuthType(t string) (AuthType, error) {
+	switch t {
+	case "bearer":
+		return BearerAuth, nil
+	case "basic":
+		return BasicAuth, nil
+	case "api_key":
+		return ApiKeyAuth, nil
+	default:
+		return InvalidAuth, fmt.Errorf("invalid auth type detected \"%s\"", t)
+	}
+}
+
+// apply adds the credentials of the auth to the request.
+func (a Auth) apply(req *http.Request) {
+	switch a.Type {
+	case BearerAuth:
+		req.Header.Set("Authorization", "Bearer "+a.Secret)
+	case BasicAuth:
+		req.SetBasicAuth(a.Username, a.Secret)
+	case ApiKeyAuth:
+		req.Header.Set(a.Header, a.Secret)
+	}
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kingcobra2468/cot/internal/config"
+)
+
+// ErrUnavailable is returned when requests to a failing client service are stopped by
+// its circuit breaker.
+var ErrUnavailable = errors.New("unavailable")
+
+const (
+	// defaultFailureThreshold is the number of consecutive failures after which the circuit
+	// breaker opens when none is specified.
+	defaultFailureThreshold = 5
+	// defaultCoolOff is how long the circuit breaker stays open when none is specified.
+	defaultCoolOff = time.Second * 30
+)
+
+// Circuit breaker state.
+type breakerState int8
+
+// breakerState lists the different states of a circuit breaker.
+const (
+	// closedState describes a breaker that lets all requests through.
+	closedState breakerState = iota
+	// openState describes a breaker that stops all requests until its cool-off elapses.
+	openState
+	// halfOpenState describes a breaker that lets a single trial request through to decide
+	// whether to close or to open again.
+	halfOpenState
+)
+
+// Breaker describes when requests to a failing client service are stopped and when they
+// are attempted again.
+type Breaker struct {
+	// Number of consecutive failures after which requests are stopped.
+	FailureThreshold int
+	// How long requests are stopped before a trial request is let through.
+	CoolOff time.Duration
+}
+
+// breaker tracks the failures of the requests to a client service. Once the failure
+// threshold is reached, the breaker opens and stops requests until the cool-off elapses.
+// It then half-opens to let a single trial request through, which closes the breaker on
+// success and opens it again on failure. The breaker is goroutine-safe.
+type breaker struct {
+	Breaker
+	state    breakerState
+	failures int
+	openedAt time.Time
+	mtx      sync.Mutex
+}
+
+// generateBreaker parses and validates the circuit breaker of a service from the
+// configuration file.
+func generateBreaker(breakerInfo *config.Breaker) (Breaker, error) {
+	b := Breaker{FailureThreshold: defaultFailureThreshold, CoolOff: defaultCoolOff}
+	if breakerInfo == nil {
+		return b, nil
+	}
+
+	if breakerInfo.FailureThreshold < 0 {
+		return b, fmt.Errorf("invalid circuit breaker failure threshold %d", breakerInfo.FailureThreshold)
+	}
+	if breakerInfo.FailureThreshold > 0 {
+		b.FailureThreshold = breakerInfo.FailureThreshold
+	}
+	if breakerInfo.CoolOff != "" {
+		coolOff, err := time.ParseDuration(breakerInfo.CoolOff)
+		if err != nil || coolOff <= 0 {
+			return b, fmt.Errorf("invalid circuit breaker cool off \"%s\"", breakerInfo.CoolOff)
+		}
+		b.CoolOff = coolOff
+	}
+
+	return b, nil
+}
+
+// newBreaker creates a closed circuit breaker.
+func newBreaker(b Breaker) *breaker {
+	if b.FailureThreshold == 0 {
+		b.FailureThreshold = defaultFailureThreshold
+	}
+	if b.CoolOff == 0 {
+		b.CoolOff = defaultCoolOff
+	}
+
+	return &breaker{Breaker: b}
+}
+
+// allow checks whether a request can be sent. An open breaker whose cool-off has elapsed
+// half-opens and allows the request as the trial request.
+func (b *breaker) allow() bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	switch b.state {
+	case openState:
+		if time.Since(b.openedAt) < b.CoolOff {
+			return false
+		}
+		b.state = halfOpenState
+		return true
+	case halfOpenState:
+		// a trial request is already in flight
+		return false
+	default:
+		return true
+	}
+}
+
+// record tracks the outcome of a request that was allowed through the breaker.
+func (b *breaker) record(failed bool) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if !failed {
+		b.state = closedState
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == halfOpenState || b.failures >= b.FailureThreshold {
+		b.state = openState
+		b.openedAt = time.Now()
+	}
+}
@@ -4,42 +4,102 @@ package service
 
 import (
 	"errors"
+	"fmt"
 	"sync"
 )
 
-// Cache contains a goroutine-safe client pool for services. This is
-// then used when sending commands to the client services efficiently
-// as a minimum amount of clients are created via the use of a pool.
+// Cache contains a goroutine-safe registry of services. Each service holds a single
+// long-lived client which is shared by all of the commands sent to it, so that its
+// connections are pooled and reused. Each service also holds a circuit breaker which
+// tracks the failures of the commands sent to it.
 type Cache struct {
-	// store a pool that can be referenced by the service/command name
-	cache map[string]*sync.Pool
-	mtx   sync.Mutex
+	// store a service that can be referenced by the service/command name
+	cache map[string]*Service
+	// mapping between a service alias and the name of the service
+	aliases map[string]string
+	mtx     sync.Mutex
 }
 
 var (
 	errInvalidService = errors.New("invalid service")
 )
 
+// builtinCommands are the commands that are handled by cot itself, which services cannot
+// be named after.
+var builtinCommands = []string{"ping", "help"}
+
 // NewCache creates a new Cache instance.
 func NewCache() *Cache {
-	return &Cache{cache: make(map[string]*sync.Pool), mtx: sync.Mutex{}}
+	return &Cache{cache: make(map[string]*Service), aliases: make(map[string]string), mtx: sync.Mutex{}}
 }
 
-// Add service(s) to the cache. This creates a new client pool for each service.
-// This method is goroutine-safe.
-func (c *Cache) Add(services ...Service) {
+// Add service(s) to the cache. Services without a client of their own are given a
+// new client with the default transport settings. Each service is given a new circuit
+// breaker. An error is returned if the name or an alias of a service collides with that
+// of a service that was already added. This method is goroutine-safe.
+func (c *Cache) Add(services ...Service) error {
 	for _, s := range services {
 		c.mtx.Lock()
-		c.cache[s.Name] = newServicePool(s)
+		if err := c.checkNames(s); err != nil {
+			c.mtx.Unlock()
+			return err
+		}
+		svc := s
+		if svc.client == nil {
+			// the default transport settings cannot fail to be set up
+			svc.client, _ = newClient(nil, nil)
+		}
+		svc.breaker = newBreaker(svc.Breaker)
+		c.cache[s.Name] = &svc
+		for _, alias := range s.Aliases {
+			c.aliases[alias] = s.Name
+		}
 		c.mtx.Unlock()
 	}
+
+	return nil
+}
+
+// checkNames validates that neither the name nor the aliases of a service are already
+// in use by the services within the cache or by the built-in commands.
+func (c *Cache) checkNames(s Service) error {
+	names := append([]string{s.Name}, s.Aliases...)
+	for i, name := range names {
+		if containsFold(builtinCommands, name) {
+			return fmt.Errorf("name \"%s\" of service \"%s\" collides with a built-in command", name, s.Name)
+		}
+		if _, exists := c.cache[name]; exists {
+			return fmt.Errorf("name \"%s\" of service \"%s\" collides with an existing service", name, s.Name)
+		}
+		if other, exists := c.aliases[name]; exists {
+			return fmt.Errorf("name \"%s\" of service \"%s\" collides with an alias of service \"%s\"", name, s.Name, other)
+		}
+		if containsFold(names[i+1:], name) {
+			return fmt.Errorf("name \"%s\" is repeated in service \"%s\"", name, s.Name)
+		}
+	}
+
+	return nil
+}
+
+// Resolve rewrites the name of an input command that references a service by one of its
+// aliases into the name of the service. The raw input command is rewritten likewise so
+// that the command patterns can match it.
+func (c *Cache) Resolve(ui *UserInput) {
+	name, ok := c.aliases[ui.Name]
+	if !ok {
+		return
+	}
+
+	ui.Raw = replaceToken(ui.Raw, 0, name)
+	ui.Name = name
 }
 
 // Get fetches the underlying service under the name provided in configuration if
-// such a service exists.
-func (c *Cache) Get(name string) (*sync.Pool, error) {
-	if client, ok := c.cache[name]; ok {
-		return client, nil
+// such a service exists. The service is shared and must not be modified.
+func (c *Cache) Get(name string) (*Service, error) {
+	if s, ok := c.cache[name]; ok {
+		return s, nil
 	}
 
 	return nil, errInvalidService
@@ -54,11 +114,3 @@ func (c *Cache) Services() []string {
 
 	return names
 }
-
-// newServicePool creates a new client pool for a given service.
-func newServicePool(s Service) *sync.Pool {
-	return &sync.Pool{New: func() interface{} {
-		s := Service(s)
-		return &s
-	}}
-}
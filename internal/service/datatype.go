@@ -0,0 +1,230 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kingcobra2468/cot/internal/config"
+)
+
+// defaultLayouts are the layouts that datetime args are parsed with when none are specified.
+var defaultLayouts = []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"}
+
+// setupDataType validates and sets up the datatype specific options of an arg from the
+// configuration file.
+func (a *Arg) setupDataType(argInfo config.Arg) error {
+	switch a.DataType {
+	case ListType:
+		dt, err := parseItemType(argInfo.DataType)
+		if err != nil {
+			return fmt.Errorf("invalid datatype of %s: %w", a.label(), err)
+		}
+		a.ItemType = dt
+	case EnumType:
+		if len(argInfo.Values) == 0 {
+			return fmt.Errorf("enum datatype of %s requires a list of values", a.label())
+		}
+		a.Values = stringify(argInfo.Values)
+	case DateTimeType:
+		a.Layouts = defaultLayouts
+		if len(argInfo.Layouts) > 0 {
+			a.Layouts = argInfo.Layouts
+		}
+		a.Format = time.RFC3339
+		if argInfo.Format != "" {
+			a.Format = argInfo.Format
+		}
+		a.Location = time.Local
+		if argInfo.Timezone != "" {
+			loc, err := time.LoadLocation(argInfo.Timezone)
+			if err != nil {
+				return fmt.Errorf("invalid timezone \"%s\" for %s: %w", argInfo.Timezone, a.label(), err)
+			}
+			a.Location = loc
+		}
+	}
+
+	return nil
+}
+
+// parseItemType processes the datatype of each of the values of a list datatype (e.g.
+// "list:int"). The values of a list are strings unless specified otherwise.
+func parseItemType(t string) (ArgDataType, error) {
+	_, base, found := strings.Cut(t, ":")
+	if !found {
+		return StringType, nil
+	}
+
+	dt, err := parseArgDataType(base)
+	if err != nil {
+		return InvalidType, err
+	}
+	if dt == ListType || dt == JsonType {
+		return InvalidType, fmt.Errorf("list datatype cannot contain values of datatype \"%s\"", base)
+	}
+
+	return dt, nil
+}
+
+// parse validates a raw arg value and converts it into the datatype of the arg. Each value
+// of a list arg is validated individually.
+func (a Arg) parse(raw string) (interface{}, error) {
+	vals := []string{raw}
+	if a.DataType == ListType {
+		vals = splitList(raw)
+	}
+	for _, val := range vals {
+		if err := a.check(val); err != nil {
+			return nil, err
+		}
+	}
+
+	return a.cast(raw)
+}
+
+// cast converts a raw arg value into the datatype of the arg.
+func (a Arg) cast(raw string) (interface{}, error) {
+	if a.DataType == ListType {
+		vals := []interface{}{}
+		for _, item := range splitList(raw) {
+			val, err := a.castValue(a.ItemType, item)
+			if err != nil {
+				return nil, err
+			}
+			vals = append(vals, val)
+		}
+		return vals, nil
+	}
+
+	return a.castValue(a.DataType, raw)
+}
+
+// castValue converts a raw value into a given datatype.
+func (a Arg) castValue(dt ArgDataType, raw string) (interface{}, error) {
+	switch dt {
+	case IntType:
+		val, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse arg %s into an int", raw)
+		}
+		return val, nil
+	case FloatType:
+		val, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse arg %s into an float", raw)
+		}
+		return val, nil
+	case BoolType:
+		val, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse arg %s into an boolean", raw)
+		}
+		return val, nil
+	case DurationType:
+		val, err := time.ParseDuration(raw)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse arg %s into a duration", raw)
+		}
+		return val.Seconds(), nil
+	case DateTimeType:
+		for _, layout := range a.Layouts {
+			if val, err := time.ParseInLocation(layout, raw, a.Location); err == nil {
+				return val.Format(a.Format), nil
+			}
+		}
+		return nil, fmt.Errorf("unable to parse arg %s into a datetime", raw)
+	case EnumType:
+		for _, val := range a.Values {
+			if strings.EqualFold(val, raw) {
+				return val, nil
+			}
+		}
+		return nil, fmt.Errorf("unable to parse arg %s into one of %s", raw, strings.Join(a.Values, ", "))
+	case JsonType:
+		var val map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &val); err != nil {
+			return nil, fmt.Errorf("unable to parse arg %s into a JSON object", raw)
+		}
+		return val, nil
+	default:
+		return raw, nil
+	}
+}
+
+// elemType fetches the datatype of each individual value of an arg.
+func (a Arg) elemType() ArgDataType {
+	if a.DataType == ListType {
+		return a.ItemType
+	}
+
+	return a.DataType
+}
+
+// splitList splits a raw comma-separated list into its non-empty values.
+func splitList(raw string) []string {
+	vals := []string{}
+	for _, val := range strings.Split(raw, ",") {
+		if val = strings.TrimSpace(val); val != "" {
+			vals = append(vals, val)
+		}
+	}
+
+	return vals
+}
+
+// formatConfigValue converts a value from the configuration file into its raw string form
+// so that it can be parsed like a raw arg value. Lists are joined by the list separator and
+// floats are formatted without an exponent.
+func formatConfigValue(val interface{}) string {
+	switch v := val.(type) {
+	case []interface{}:
+		strs := make([]string, 0, len(v))
+		for _, item := range v {
+			strs = append(strs, formatConfigValue(item))
+		}
+		return strings.Join(strs, ",")
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// format converts a cast arg value back into its string form for use within the query
+// params or endpoint URL.
+func format(val interface{}) (string, error) {
+	switch v := val.(type) {
+	case string:
+		return v, nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			s, err := format(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, ","), nil
+	case map[string]interface{}:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	default:
+		return "", errors.New("unable to format arg of unknown datatype")
+	}
+}
@@ -0,0 +1,358 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Jeffail/gabs"
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+)
+
+// csvPathRegex matches a CSV path, which is an optional column followed by an optional
+// row selector (e.g. "status", "status[0]", "status[name=ups1]" or "[0]").
+var csvPathRegex = regexp.MustCompile(`^([^\[\]]*)(?:\[([^\[\]]*)\])?$`)
+
+// document is a parsed response body from which values are selected by their path.
+type document interface {
+	// find selects the value at a path of the document, which is nil if it does not exist.
+	find(path string) (interface{}, error)
+	// data fetches the document as the value that JSON path expressions and response
+	// templates are evaluated against.
+	data() interface{}
+}
+
+// parseDocument parses a response body based on the response type.
+func parseDocument(rt ResponseType, body []byte) (document, error) {
+	switch rt {
+	case XmlResponse:
+		return parseXml(body)
+	case CsvResponse:
+		return parseCsv(body)
+	case KvResponse:
+		return parseKv(body), nil
+	default:
+		output, err := gabs.ParseJSON(body)
+		if err != nil {
+			return nil, err
+		}
+		return jsonDocument{output}, nil
+	}
+}
+
+// checkResponsePath validates a response path based on the response type and compiles it
+// if it is a JSON path expression. JSON path expressions are not supported for XML
+// responses, whose paths are XPath expressions instead.
+func checkResponsePath(rt ResponseType, path string) (*JsonPath, error) {
+	switch {
+	case rt == XmlResponse:
+		if path == "" {
+			return nil, nil
+		}
+		if _, err := xpath.Compile(path); err != nil {
+			return nil, fmt.Errorf("invalid xpath expression \"%s\": %w", path, err)
+		}
+		return nil, nil
+	case rt == CsvResponse && !isJsonPath(path):
+		_, err := parseCsvPath(path)
+		return nil, err
+	case !isJsonPath(path):
+		return nil, nil
+	default:
+		return compileJsonPath(path)
+	}
+}
+
+// jsonDocument is a parsed JSON response whose values are selected by dot paths.
+type jsonDocument struct {
+	output *gabs.Container
+}
+
+// find selects the value at a dot path of the document. An empty path selects the whole
+// document.
+func (d jsonDocument) find(path string) (interface{}, error) {
+	if path == "" {
+		return d.output.Data(), nil
+	}
+
+	return d.output.Path(path).Data(), nil
+}
+
+func (d jsonDocument) data() interface{} {
+	return d.output.Data()
+}
+
+// xmlDocument is a parsed XML response whose values are selected by XPath expressions.
+type xmlDocument struct {
+	root *xmlquery.Node
+	raw  string
+}
+
+// parseXml parses an XML response.
+func parseXml(body []byte) (*xmlDocument, error) {
+	root, err := xmlquery.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse xml response: %w", err)
+	}
+
+	return &xmlDocument{root: root, raw: string(body)}, nil
+}
+
+// find evaluates an XPath expression against the document. A single selected node is its
+// trimmed text, while several selected nodes are a list of their text. Expressions that
+// compute a value (e.g. "count(//outlet)") are that value. An empty path selects the text
+// of the whole document.
+func (d *xmlDocument) find(path string) (interface{}, error) {
+	if path == "" {
+		return strings.TrimSpace(d.root.InnerText()), nil
+	}
+
+	// expressions are compiled for each evaluation as compiled expressions are not safe
+	// to evaluate concurrently
+	expr, err := xpath.Compile(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid xpath expression \"%s\": %w", path, err)
+	}
+
+	result := expr.Evaluate(xmlquery.CreateXPathNavigator(d.root))
+	iter, ok := result.(*xpath.NodeIterator)
+	if !ok {
+		return result, nil
+	}
+	vals := []interface{}{}
+	for iter.MoveNext() {
+		vals = append(vals, strings.TrimSpace(iter.Current().Value()))
+	}
+	switch len(vals) {
+	case 0:
+		return nil, nil
+	case 1:
+		return vals[0], nil
+	default:
+		return vals, nil
+	}
+}
+
+// data fetches the raw XML response.
+func (d *xmlDocument) data() interface{} {
+	return d.raw
+}
+
+// csvPath is a parsed CSV path, which selects a column of the selected rows. Without a
+// column, whole rows are selected as objects keyed by the header.
+type csvPath struct {
+	column string
+	// Index of the selected row, if a single row is selected.
+	row *int
+	// Column and value that the selected rows are filtered by, if any.
+	filterColumn string
+	filterValue  string
+}
+
+// parseCsvPath parses a CSV path of the form "column", "column[row]" or
+// "column[column=value]", where the column is optional.
+func parseCsvPath(path string) (*csvPath, error) {
+	m := csvPathRegex.FindStringSubmatch(strings.TrimSpace(path))
+	if m == nil {
+		return nil, fmt.Errorf("invalid csv path \"%s\"", path)
+	}
+
+	p := csvPath{column: strings.TrimSpace(m[1])}
+	if selector := strings.TrimSpace(m[2]); selector != "" {
+		if col, val, found := strings.Cut(selector, "="); found {
+			p.filterColumn, p.filterValue = strings.TrimSpace(col), strings.TrimSpace(val)
+			if p.filterColumn == "" {
+				return nil, fmt.Errorf("invalid csv path \"%s\"", path)
+			}
+		} else if row, err := strconv.Atoi(selector); err == nil {
+			p.row = &row
+		} else {
+			return nil, fmt.Errorf("invalid csv path \"%s\"", path)
+		}
+	}
+
+	return &p, nil
+}
+
+// csvDocument is a parsed CSV response whose first row is the header.
+type csvDocument struct {
+	header []string
+	rows   [][]string
+}
+
+// parseCsv parses a CSV response.
+func parseCsv(body []byte) (*csvDocument, error) {
+	r := csv.NewReader(bytes.NewReader(body))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse csv response: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("unable to parse csv response: missing header")
+	}
+
+	return &csvDocument{header: records[0], rows: records[1:]}, nil
+}
+
+// find selects the values of a column of the rows that a CSV path selects. A single
+// selected row is a single value, while otherwise the values are a list.
+func (d *csvDocument) find(path string) (interface{}, error) {
+	p, err := parseCsvPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := d.rows
+	if p.row != nil {
+		i := *p.row
+		if i < 0 {
+			i += len(rows)
+		}
+		if i < 0 || i >= len(rows) {
+			return nil, nil
+		}
+		rows = rows[i : i+1]
+	} else if p.filterColumn != "" {
+		col := d.column(p.filterColumn)
+		filtered := [][]string{}
+		for _, row := range rows {
+			if col >= 0 && col < len(row) && row[col] == p.filterValue {
+				filtered = append(filtered, row)
+			}
+		}
+		rows = filtered
+	}
+
+	col := -1
+	if p.column != "" {
+		if col = d.column(p.column); col == -1 {
+			return nil, nil
+		}
+	}
+	vals := []interface{}{}
+	for _, row := range rows {
+		switch {
+		case col == -1:
+			vals = append(vals, d.object(row))
+		case col < len(row):
+			vals = append(vals, row[col])
+		}
+	}
+	if p.row != nil {
+		if len(vals) == 0 {
+			return nil, nil
+		}
+		return vals[0], nil
+	}
+
+	return vals, nil
+}
+
+// column fetches the index of a column by its header, ignoring case, or by its index.
+// Missing columns have an index of -1.
+func (d *csvDocument) column(name string) int {
+	for i, h := range d.header {
+		if h == name {
+			return i
+		}
+	}
+	for i, h := range d.header {
+		if strings.EqualFold(h, name) {
+			return i
+		}
+	}
+	if i, err := strconv.Atoi(name); err == nil && i >= 0 && i < len(d.header) {
+		return i
+	}
+
+	return -1
+}
+
+// object converts a row into an object keyed by the header.
+func (d *csvDocument) object(row []string) map[string]interface{} {
+	obj := make(map[string]interface{})
+	for i, h := range d.header {
+		if i < len(row) {
+			obj[h] = row[i]
+		}
+	}
+
+	return obj
+}
+
+// data fetches the rows as a list of objects keyed by the header.
+func (d *csvDocument) data() interface{} {
+	rows := make([]interface{}, 0, len(d.rows))
+	for _, row := range d.rows {
+		rows = append(rows, d.object(row))
+	}
+
+	return rows
+}
+
+// kvDocument is a parsed line-based response of keys and values, where the values of
+// repeated keys are a list.
+type kvDocument map[string]interface{}
+
+// parseKv parses a line-based response where each line is of the form "key=value" or
+// "key: value", whichever separator comes first. Blank lines, lines without a separator
+// and comments beginning with "#" are skipped, and quotes around values are removed.
+func parseKv(body []byte) kvDocument {
+	doc := make(kvDocument)
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.IndexAny(line, "=:")
+		if i <= 0 {
+			continue
+		}
+
+		key, val := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		if len(val) >= 2 && (val[0] == '"' || val[0] == '\'') && val[len(val)-1] == val[0] {
+			val = val[1 : len(val)-1]
+		}
+		switch existing := doc[key].(type) {
+		case nil:
+			doc[key] = val
+		case []interface{}:
+			doc[key] = append(existing, val)
+		default:
+			doc[key] = []interface{}{existing, val}
+		}
+	}
+
+	return doc
+}
+
+// find selects the value of a key, ignoring case if there is no exact match. Keys that only
+// differ by case are matched in sorted order. An empty path selects every key.
+func (d kvDocument) find(path string) (interface{}, error) {
+	if path == "" {
+		return map[string]interface{}(d), nil
+	}
+	if val, ok := d[path]; ok {
+		return val, nil
+	}
+	for _, key := range sortedKeys(d) {
+		if strings.EqualFold(key, path) {
+			return d[key], nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (d kvDocument) data() interface{} {
+	return map[string]interface{}(d)
+}
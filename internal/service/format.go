@@ -0,0 +1,234 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timestampLayouts are the layouts that timestamps within responses are parsed with.
+var timestampLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"}
+
+// formatNumber formats a number with a given number of decimal places and with its
+// thousands separated by commas (e.g. "40,000.00").
+func formatNumber(precision int, val interface{}) (string, error) {
+	f, err := toFloat(val)
+	if err != nil {
+		return "", err
+	}
+
+	s := strconv.FormatFloat(math.Abs(f), 'f', max(precision, 0), 64)
+	whole, frac, _ := strings.Cut(s, ".")
+	var b strings.Builder
+	if f < 0 && strings.Trim(s, "0.") != "" {
+		b.WriteByte('-')
+	}
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	if frac != "" {
+		b.WriteString("." + frac)
+	}
+
+	return b.String(), nil
+}
+
+// formatDate formats a timestamp with a given Go time layout in the local timezone.
+func formatDate(layout string, val interface{}) (string, error) {
+	t, err := toTime(val)
+	if err != nil {
+		return "", err
+	}
+
+	return t.Local().Format(layout), nil
+}
+
+// formatAgo formats a timestamp relative to the current time (e.g. "5m ago" or "in 2h").
+func formatAgo(val interface{}) (string, error) {
+	t, err := toTime(val)
+	if err != nil {
+		return "", err
+	}
+
+	d := time.Since(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+	var s string
+	switch {
+	case d < time.Minute:
+		return "just now", nil
+	case d < time.Hour:
+		s = fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		s = fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		s = fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+	if future {
+		return "in " + s, nil
+	}
+
+	return s + " ago", nil
+}
+
+// truncate shortens a value to at most n characters, ending it with "..." when it is
+// shortened.
+func truncate(n int, val interface{}) string {
+	s := []rune(fmt.Sprint(val))
+	if len(s) <= n {
+		return string(s)
+	}
+	if n <= 3 {
+		return string(s[:max(n, 0)])
+	}
+
+	return string(s[:n-3]) + "..."
+}
+
+// toFloat converts a numeric value from a response or arg into a float.
+func toFloat(val interface{}) (float64, error) {
+	switch v := val.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case json.Number:
+		return v.Float64()
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("unable to format \"%s\" as a number", v)
+		}
+		return f, nil
+	default:
+		return 0, fmt.Errorf("unable to format %v as a number", val)
+	}
+}
+
+// toTime converts a timestamp from a response or arg into a time. Numeric timestamps are
+// treated as Unix time in seconds, or in milliseconds if they are too large to be seconds.
+func toTime(val interface{}) (time.Time, error) {
+	if s, ok := val.(string); ok {
+		for _, layout := range timestampLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, nil
+			}
+		}
+	}
+	if t, ok := val.(time.Time); ok {
+		return t, nil
+	}
+
+	f, err := toFloat(val)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to format %v as a date", val)
+	}
+	if math.Abs(f) >= 1e11 {
+		return time.UnixMilli(int64(f)), nil
+	}
+
+	return time.Unix(int64(f), 0), nil
+}
+
+// format formats a value extracted from a response for a reply based on a given datatype.
+// Arrays are formatted as a line per value, and objects as a line per key in the form of
+// "key: value", with each value formatted based on the datatype of the list values. Values
+// nested any deeper, as well as values of the json datatype, are formatted as compact JSON.
+func (e Extract) format(dt, itemType ArgDataType, val interface{}) (string, error) {
+	if dt == JsonType {
+		return compactJson(val)
+	}
+
+	lines := []string{}
+	switch v := val.(type) {
+	case []interface{}:
+		for _, item := range v {
+			line, err := e.formatScalar(itemType, item)
+			if err != nil {
+				return "", err
+			}
+			lines = append(lines, line)
+		}
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			line, err := e.formatScalar(itemType, v[key])
+			if err != nil {
+				return "", err
+			}
+			lines = append(lines, key+": "+line)
+		}
+	default:
+		return e.formatScalar(dt, val)
+	}
+	if len(lines) == 0 {
+		return "none", nil
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// formatScalar formats a single value extracted from a response based on a given datatype.
+// Values that cannot be converted into the datatype are formatted as they are.
+func (e Extract) formatScalar(dt ArgDataType, val interface{}) (string, error) {
+	switch v := val.(type) {
+	case nil:
+		return "none", nil
+	case []interface{}, map[string]interface{}:
+		return compactJson(v)
+	case bool:
+		if v {
+			return e.TrueWord, nil
+		}
+		return e.FalseWord, nil
+	}
+
+	switch dt {
+	case IntType:
+		if f, err := toFloat(val); err == nil {
+			return strconv.FormatFloat(math.Round(f), 'f', 0, 64), nil
+		}
+	case FloatType:
+		if f, err := toFloat(val); err == nil {
+			return strconv.FormatFloat(f, 'f', e.Precision, 64), nil
+		}
+	case BoolType:
+		if s, ok := val.(string); ok {
+			if b, err := strconv.ParseBool(s); err == nil {
+				return e.formatScalar(dt, b)
+			}
+		}
+	case DurationType:
+		if f, err := toFloat(val); err == nil {
+			return time.Duration(f * float64(time.Second)).String(), nil
+		}
+	}
+
+	switch v := val.(type) {
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', e.Precision, 64), nil
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// compactJson serializes a value into compact JSON.
+func compactJson(val interface{}) (string, error) {
+	b, err := json.Marshal(val)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
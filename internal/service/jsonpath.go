@@ -0,0 +1,420 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// JSON path expression step kind.
+type stepKind int8
+
+// stepKind lists the different steps of a JSON path expression.
+const (
+	// childStep selects a key of an object (e.g. ".name" or "['name']").
+	childStep stepKind = iota
+	// indexStep selects an element of an array (e.g. "[0]" or "[-1]").
+	indexStep
+	// wildcardStep selects every value of an object or array (e.g. "[*]" or ".*").
+	wildcardStep
+	// filterStep selects the values of an object or array that satisfy a condition
+	// (e.g. "[?(@.price < 50000)]").
+	filterStep
+	// lengthStep selects the length of an array, object or string (e.g. ".length()").
+	lengthStep
+)
+
+// filterOperators are the comparison operators of filter conditions. Operators that are
+// prefixes of other operators come last.
+var filterOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// JsonPath is a compiled JSONPath-style expression that selects values from a parsed JSON
+// document. Expressions begin with "$" and support child keys, array indexes, wildcards,
+// filters and lengths (e.g. "$.cars[?(@.price < 50000)].name" or "$.cars.length()").
+type JsonPath struct {
+	raw   string
+	steps []step
+	// Whether the expression can select more than a single value.
+	multi bool
+}
+
+// step is a single step of a JSON path expression.
+type step struct {
+	kind  stepKind
+	key   string
+	index int
+	cond  *condition
+}
+
+// condition is the condition of a filter step, which compares the value selected by a
+// relative path against a literal. Without an operator, the condition checks whether the
+// relative path selects any value.
+type condition struct {
+	path     *JsonPath
+	operator string
+	literal  interface{}
+}
+
+// isJsonPath checks whether a response path is a JSON path expression rather than a plain
+// dot path.
+func isJsonPath(path string) bool {
+	return strings.HasPrefix(path, "$")
+}
+
+// compileJsonPath parses a JSON path expression.
+func compileJsonPath(expr string) (*JsonPath, error) {
+	p, err := compilePath(expr, '$')
+	if err != nil {
+		return nil, fmt.Errorf("invalid path expression \"%s\": %w", expr, err)
+	}
+
+	return p, nil
+}
+
+// compilePath parses a path expression that begins with the given root (either "$" for
+// the document or "@" for the current value of a filter).
+func compilePath(expr string, root byte) (*JsonPath, error) {
+	expr = strings.TrimSpace(expr)
+	if len(expr) == 0 || expr[0] != root {
+		return nil, fmt.Errorf("path must begin with '%c'", root)
+	}
+
+	p := JsonPath{raw: expr}
+	for i := 1; i < len(expr); {
+		if len(p.steps) > 0 && p.steps[len(p.steps)-1].kind == lengthStep {
+			return nil, errors.New("length() must be the last step")
+		}
+
+		switch expr[i] {
+		case '.':
+			end := i + 1
+			for end < len(expr) && expr[end] != '.' && expr[end] != '[' {
+				end++
+			}
+			key := expr[i+1 : end]
+			switch key {
+			case "":
+				return nil, fmt.Errorf("empty key at offset %d", i)
+			case "*":
+				p.steps = append(p.steps, step{kind: wildcardStep})
+				p.multi = true
+			case "length()":
+				p.steps = append(p.steps, step{kind: lengthStep})
+			default:
+				p.steps = append(p.steps, step{kind: childStep, key: key})
+			}
+			i = end
+		case '[':
+			end := closingBracket(expr, i)
+			if end == -1 {
+				return nil, fmt.Errorf("unclosed '[' at offset %d", i)
+			}
+			s, err := compileBracket(strings.TrimSpace(expr[i+1 : end]))
+			if err != nil {
+				return nil, err
+			}
+			if s.kind == wildcardStep || s.kind == filterStep {
+				p.multi = true
+			}
+			p.steps = append(p.steps, s)
+			i = end + 1
+		default:
+			return nil, fmt.Errorf("unexpected '%c' at offset %d", expr[i], i)
+		}
+	}
+
+	return &p, nil
+}
+
+// closingBracket finds the offset of the bracket that closes the bracket at a given offset,
+// skipping over brackets within quotes.
+func closingBracket(expr string, open int) int {
+	depth := 0
+	var quote byte
+	for i := open; i < len(expr); i++ {
+		switch c := expr[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// compileBracket parses the contents of a bracket step.
+func compileBracket(inner string) (step, error) {
+	switch {
+	case inner == "*":
+		return step{kind: wildcardStep}, nil
+	case strings.HasPrefix(inner, "?(") && strings.HasSuffix(inner, ")"):
+		cond, err := compileCondition(inner[2 : len(inner)-1])
+		if err != nil {
+			return step{}, err
+		}
+		return step{kind: filterStep, cond: cond}, nil
+	case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+		return step{kind: childStep, key: inner[1 : len(inner)-1]}, nil
+	default:
+		index, err := strconv.Atoi(inner)
+		if err != nil {
+			return step{}, fmt.Errorf("invalid bracket \"[%s]\"", inner)
+		}
+		return step{kind: indexStep, index: index}, nil
+	}
+}
+
+// compileCondition parses the condition of a filter step.
+func compileCondition(expr string) (*condition, error) {
+	cond := condition{}
+	left := expr
+	if i, op := findOperator(expr); i != -1 {
+		literal, err := parseLiteral(strings.TrimSpace(expr[i+len(op):]))
+		if err != nil {
+			return nil, err
+		}
+		left, cond.operator, cond.literal = expr[:i], op, literal
+	}
+
+	path, err := compilePath(left, '@')
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter \"%s\": %w", expr, err)
+	}
+	cond.path = path
+
+	return &cond, nil
+}
+
+// findOperator finds the offset of the first comparison operator of a filter condition,
+// skipping over operators within quotes.
+func findOperator(expr string) (int, string) {
+	var quote byte
+	for i := 0; i < len(expr); i++ {
+		switch c := expr[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		default:
+			for _, op := range filterOperators {
+				if strings.HasPrefix(expr[i:], op) {
+					return i, op
+				}
+			}
+		}
+	}
+
+	return -1, ""
+}
+
+// parseLiteral parses the literal of a filter condition, which is either a JSON literal or
+// a single-quoted string.
+func parseLiteral(raw string) (interface{}, error) {
+	if len(raw) >= 2 && raw[0] == '\'' && raw[len(raw)-1] == '\'' {
+		return raw[1 : len(raw)-1], nil
+	}
+
+	var literal interface{}
+	if err := json.Unmarshal([]byte(raw), &literal); err != nil {
+		return nil, fmt.Errorf("invalid filter literal \"%s\"", raw)
+	}
+
+	return literal, nil
+}
+
+// Select evaluates the expression against a parsed JSON document. Expressions that can
+// select more than a single value return a list of the selected values, while other
+// expressions return the selected value or nil if it does not exist.
+func (p *JsonPath) Select(doc interface{}) interface{} {
+	nodes := p.eval(doc)
+	if p.multi {
+		return nodes
+	}
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	return nodes[0]
+}
+
+// String fetches the raw expression.
+func (p *JsonPath) String() string {
+	return p.raw
+}
+
+// eval evaluates each of the steps of the expression against a value.
+func (p *JsonPath) eval(doc interface{}) []interface{} {
+	nodes := []interface{}{doc}
+	for _, s := range p.steps {
+		next := []interface{}{}
+		for _, node := range nodes {
+			next = append(next, s.apply(node)...)
+		}
+		nodes = next
+	}
+
+	return nodes
+}
+
+// apply selects the values from a given value that the step refers to.
+func (s step) apply(node interface{}) []interface{} {
+	switch s.kind {
+	case childStep:
+		if obj, ok := node.(map[string]interface{}); ok {
+			if val, exists := obj[s.key]; exists {
+				return []interface{}{val}
+			}
+		}
+	case indexStep:
+		if arr, ok := node.([]interface{}); ok {
+			i := s.index
+			if i < 0 {
+				i += len(arr)
+			}
+			if i >= 0 && i < len(arr) {
+				return []interface{}{arr[i]}
+			}
+		}
+	case wildcardStep, filterStep:
+		vals := []interface{}{}
+		for _, val := range children(node) {
+			if s.kind == wildcardStep || s.cond.matches(val) {
+				vals = append(vals, val)
+			}
+		}
+		return vals
+	case lengthStep:
+		switch v := node.(type) {
+		case []interface{}:
+			return []interface{}{float64(len(v))}
+		case map[string]interface{}:
+			return []interface{}{float64(len(v))}
+		case string:
+			return []interface{}{float64(utf8.RuneCountInString(v))}
+		}
+	}
+
+	return []interface{}{}
+}
+
+// children fetches the values of an object, in the order of their keys, or the elements
+// of an array.
+func children(node interface{}) []interface{} {
+	switch v := node.(type) {
+	case []interface{}:
+		return v
+	case map[string]interface{}:
+		vals := make([]interface{}, 0, len(v))
+		for _, key := range sortedKeys(v) {
+			vals = append(vals, v[key])
+		}
+		return vals
+	default:
+		return nil
+	}
+}
+
+// matches checks whether a value satisfies the condition.
+func (c *condition) matches(node interface{}) bool {
+	vals := c.path.eval(node)
+	if c.operator == "" {
+		return len(vals) > 0
+	}
+
+	for _, val := range vals {
+		if compare(val, c.operator, c.literal) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compare compares a value against a literal with an operator. Numbers and strings can be
+// ordered, while any values can be compared for equality.
+func compare(val interface{}, op string, literal interface{}) bool {
+	switch op {
+	case "==":
+		return equal(val, literal)
+	case "!=":
+		return !equal(val, literal)
+	}
+
+	var cmp int
+	switch l := literal.(type) {
+	case float64:
+		v, ok := val.(float64)
+		if !ok {
+			return false
+		}
+		cmp = compareOrdered(v, l)
+	case string:
+		v, ok := val.(string)
+		if !ok {
+			return false
+		}
+		cmp = strings.Compare(v, l)
+	default:
+		return false
+	}
+
+	switch op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+// compareOrdered compares two numbers.
+func compareOrdered(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// equal checks whether a value is equal to a literal. Only scalar values can be equal.
+func equal(val, literal interface{}) bool {
+	switch val.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	}
+
+	return val == literal
+}
+
+// sortedKeys fetches the keys of an object in sorted order.
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kingcobra2468/cot/internal/config"
+)
+
+// generateExtract parses and validates how the reply is extracted from a response from
+// the configuration file based on the response type. Values and fields without a datatype
+// are formatted as strings. Without a path, the whole response is extracted, so that the
+// success and error paths are optional when replies cover the responses.
+func generateExtract(extractInfo config.Extract, rt ResponseType) (Extract, error) {
+	if extractInfo.DataType == "" {
+		extractInfo.DataType = "str"
+	}
+	dt, itemType, err := parseResponseDataType(extractInfo.DataType)
+	if err != nil {
+		return Extract{}, err
+	}
+	extract := Extract{TypeInfo: TypeInfo{DataType: dt, Path: extractInfo.Path}, ItemType: itemType, Precision: -1,
+		TrueWord: "true", FalseWord: "false"}
+	if extract.Expr, err = checkResponsePath(rt, extractInfo.Path); err != nil {
+		return Extract{}, err
+	}
+	if extractInfo.Precision != nil {
+		if *extractInfo.Precision < 0 {
+			return Extract{}, fmt.Errorf("invalid response precision %d", *extractInfo.Precision)
+		}
+		extract.Precision = *extractInfo.Precision
+	}
+	if extractInfo.TrueWord != "" {
+		extract.TrueWord = extractInfo.TrueWord
+	}
+	if extractInfo.FalseWord != "" {
+		extract.FalseWord = extractInfo.FalseWord
+	}
+
+	for _, f := range extractInfo.Fields {
+		if f.Label == "" || f.Path == "" {
+			return Extract{}, fmt.Errorf("response field \"%s\" requires both a label and a path", f.Label+f.Path)
+		}
+		if f.DataType == "" {
+			f.DataType = "str"
+		}
+		field := Field{Label: f.Label}
+		if field.DataType, field.ItemType, err = parseResponseDataType(f.DataType); err != nil {
+			return Extract{}, err
+		}
+		field.Path = f.Path
+		if field.Expr, err = checkResponsePath(rt, f.Path); err != nil {
+			return Extract{}, err
+		}
+		extract.Fields = append(extract.Fields, field)
+	}
+
+	return extract, nil
+}
+
+// parseResponseDataType processes the raw datatype of a response value into one of the
+// supported datatypes, as well as the datatype of the values of a list.
+func parseResponseDataType(t string) (ArgDataType, ArgDataType, error) {
+	dt, err := parseArgDataType(t)
+	if err != nil {
+		return InvalidType, InvalidType, err
+	}
+	if dt != ListType {
+		return dt, dt, nil
+	}
+
+	itemType, err := parseItemType(t)
+	if err != nil {
+		return InvalidType, InvalidType, err
+	}
+
+	return dt, itemType, nil
+}
+
+// extract extracts the reply from a parsed response and formats it based on its
+// datatype. When fields are specified, each field is extracted onto its own line as
+// "label: value".
+func (e Extract) extract(doc document) (string, error) {
+	if len(e.Fields) == 0 {
+		val, err := selectPath(doc, e.Path, e.Expr)
+		if err != nil {
+			return "", err
+		}
+		return e.format(e.DataType, e.ItemType, val)
+	}
+
+	lines := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		val, err := selectPath(doc, f.Path, f.Expr)
+		if err != nil {
+			return "", err
+		}
+		line, err := e.format(f.DataType, f.ItemType, val)
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, f.Label+": "+line)
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// selectPath selects the value at a path of a parsed response. JSON path expressions are
+// evaluated, while other paths are selected based on the response type.
+func selectPath(doc document, path string, expr *JsonPath) (interface{}, error) {
+	if expr == nil {
+		return doc.find(path)
+	}
+
+	return expr.Select(doc.data()), nil
+}
+
+// generateReplies parses and validates the replies of a command for given status codes
+// (e.g. "404") and classes of status codes (e.g. "5xx") from the configuration file based
+// on the response type.
+func generateReplies(replyInfo map[string]config.Reply, rt ResponseType) (map[int]*Reply, map[int]*Reply, error) {
+	replies, classReplies := make(map[int]*Reply), make(map[int]*Reply)
+	for key, r := range replyInfo {
+		extract, err := generateExtract(r.Extract, rt)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid response for status \"%s\": %w", key, err)
+		}
+		tmpl, err := generateResponseTemplate(r.Template)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid response for status \"%s\": %w", key, err)
+		}
+		if r.Message != "" && tmpl != nil {
+			return nil, nil, fmt.Errorf("response for status \"%s\" cannot have both a message and a template", key)
+		}
+		reply := &Reply{Extract: extract, Template: tmpl, Message: r.Message}
+
+		key = strings.ToLower(key)
+		if len(key) == 3 && strings.HasSuffix(key, "xx") && key[0] >= '1' && key[0] <= '5' {
+			classReplies[int(key[0]-'0')] = reply
+			continue
+		}
+		code, err := strconv.Atoi(key)
+		if err != nil || code < 100 || code > 599 {
+			return nil, nil, fmt.Errorf("invalid response status \"%s\"", key)
+		}
+		replies[code] = reply
+	}
+
+	return replies, classReplies, nil
+}
+
+// reply fetches how the reply is produced for a response with a given status code. The
+// reply for the status code takes precedence over the reply for its class. Otherwise, the
+// reply is extracted from the success path for 2xx responses and from the error path for
+// other responses, or rendered from the response template if any.
+func (r Response) reply(code int) *Reply {
+	if reply, ok := r.Replies[code]; ok {
+		return reply
+	}
+	if reply, ok := r.ClassReplies[code/100]; ok {
+		return reply
+	}
+
+	reply := Reply{Extract: r.Error, Template: r.Template}
+	if code/100 == 2 {
+		reply.Extract = r.Success
+	}
+
+	return &reply
+}
+
+// render produces the reply from the response body. A response with an empty body that is
+// not replied to with a message or template is replied to with its status (e.g.
+// "204 No Content").
+func (r *Reply) render(rt ResponseType, body []byte, code int, args map[string]interface{}) (string, error) {
+	switch {
+	case r.Message != "":
+		return r.Message, nil
+	case r.Template != nil:
+		return renderResponse(r.Template, rt, body, code, args)
+	case len(bytes.TrimSpace(body)) == 0:
+		return strings.TrimSpace(fmt.Sprintf("%d %s", code, http.StatusText(code))), nil
+	case rt == PlainTextResponse:
+		return string(body), nil
+	}
+
+	doc, err := parseDocument(rt, body)
+	if err != nil {
+		return "", err
+	}
+
+	return r.extract(doc)
+}
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kingcobra2468/cot/internal/config"
+)
+
+// ErrTimeout is returned when a client service does not respond within the timeout of
+// the command.
+var ErrTimeout = errors.New("timed out")
+
+const (
+	// defaultMaxAttempts is the number of attempts of a command request with a retry
+	// policy when none is specified.
+	defaultMaxAttempts = 3
+	// defaultBackoff is the delay before the first retry when none is specified.
+	defaultBackoff = time.Millisecond * 500
+	// defaultMaxBackoff is the upper limit of the delay between retries when none is
+	// specified.
+	defaultMaxBackoff = time.Second * 5
+)
+
+// defaultRetryStatusCodes are the response status codes that are retried when none are
+// specified.
+var defaultRetryStatusCodes = []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
+// idempotentMethods are the HTTP methods that can be retried without the risk of the
+// command being performed more than once.
+var idempotentMethods = MethodSet{
+	"get":    struct{}{},
+	"put":    struct{}{},
+	"delete": struct{}{},
+}
+
+// Retry describes how a failed command request is retried. Requests are retried when
+// they fail to connect, time out or respond with one of the retryable status codes, while
+// other errors are returned at once.
+type Retry struct {
+	// Total number of attempts, including the first attempt.
+	MaxAttempts int
+	// Delay before the first retry, which doubles after each retry up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Response status codes that are retried.
+	StatusCodes []int
+}
+
+// generateTimeout parses and validates the timeout of a command from the configuration
+// file.
+func generateTimeout(timeout string) (time.Duration, error) {
+	if timeout == "" {
+		return defaultTimeout, nil
+	}
+
+	t, err := time.ParseDuration(timeout)
+	if err != nil || t <= 0 {
+		return 0, fmt.Errorf("invalid timeout \"%s\"", timeout)
+	}
+
+	return t, nil
+}
+
+// generateRetry parses and validates the retry policy of a command from the configuration
+// file. Commands without a retry policy, and commands with a non-idempotent method unless
+// explicitly allowed, are attempted once.
+func generateRetry(retryInfo *config.Retry, method string) (Retry, error) {
+	retry := Retry{MaxAttempts: 1}
+	if retryInfo == nil {
+		return retry, nil
+	}
+
+	retry = Retry{MaxAttempts: defaultMaxAttempts, Backoff: defaultBackoff, MaxBackoff: defaultMaxBackoff,
+		StatusCodes: defaultRetryStatusCodes}
+	if retryInfo.MaxAttempts < 0 {
+		return retry, fmt.Errorf("invalid retry max attempts %d", retryInfo.MaxAttempts)
+	}
+	if retryInfo.MaxAttempts > 0 {
+		retry.MaxAttempts = retryInfo.MaxAttempts
+	}
+	for _, d := range []struct {
+		raw string
+		val *time.Duration
+	}{{retryInfo.Backoff, &retry.Backoff}, {retryInfo.MaxBackoff, &retry.MaxBackoff}} {
+		if d.raw == "" {
+			continue
+		}
+		val, err := time.ParseDuration(d.raw)
+		if err != nil || val < 0 {
+			return retry, fmt.Errorf("invalid retry backoff \"%s\"", d.raw)
+		}
+		*d.val = val
+	}
+	if retry.Backoff > retry.MaxBackoff {
+		return retry, fmt.Errorf("retry backoff %s is greater than max backoff %s", retry.Backoff, retry.MaxBackoff)
+	}
+	for _, code := range retryInfo.StatusCodes {
+		if code < 100 || code > 599 {
+			return retry, fmt.Errorf("invalid retry status code %d", code)
+		}
+	}
+	if len(retryInfo.StatusCodes) > 0 {
+		retry.StatusCodes = retryInfo.StatusCodes
+	}
+
+	if _, idempotent := idempotentMethods[strings.ToLower(method)]; !idempotent && !retryInfo.NonIdempotent {
+		retry.MaxAttempts = 1
+	}
+
+	return retry, nil
+}
+
+// send performs the command request with the client, retrying it based on the retry
+// policy of the command. Each attempt is limited by the timeout of the command. The
+// returned cancel function must be called once the response body has been read.
+func (s Service) send(client *http.Client, c *Command, req *http.Request) (*http.Response, context.CancelFunc, error) {
+	timeout := c.requestTimeout()
+	attempts := max(c.Retry.MaxAttempts, 1)
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(c.Retry.delay(attempt))
+		}
+
+		ctx, cancel := context.WithTimeout(req.Context(), timeout)
+		r := req.Clone(ctx)
+		if req.GetBody != nil {
+			if r.Body, err = req.GetBody(); err != nil {
+				cancel()
+				return nil, nil, err
+			}
+		}
+
+		var resp *http.Response
+		resp, err = client.Do(r)
+		if err == nil && (attempt == attempts-1 || !c.Retry.retryable(resp.StatusCode)) {
+			return resp, cancel, nil
+		}
+		if err != nil && !transient(err) {
+			cancel()
+			break
+		}
+		if resp != nil {
+			// drain the body so that the connection can be reused
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		cancel()
+	}
+
+	return nil, nil, s.timeoutError(c, err)
+}
+
+// requestTimeout fetches the time limit of each attempt of the command request, which
+// also covers reading the response body.
+func (c *Command) requestTimeout() time.Duration {
+	if c.Timeout == 0 {
+		return defaultTimeout
+	}
+
+	return c.Timeout
+}
+
+// timeoutError maps an error from exceeding the timeout of the command into ErrTimeout.
+// Other errors are returned as they are.
+func (s Service) timeoutError(c *Command, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("service '%s' %w after %s", s.Name, ErrTimeout, c.requestTimeout())
+	}
+
+	return err
+}
+
+// retryable checks whether a response status code is retried.
+func (r Retry) retryable(code int) bool {
+	for _, c := range r.StatusCodes {
+		if c == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// transient checks whether a request error is worth retrying, which are timeouts and
+// network errors such as refused or dropped connections. Other errors, such as invalid
+// certificates, malformed URLs or canceled requests, fail the same way on each attempt.
+func transient(err error) bool {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		// url errors implement net.Error themselves, so only the underlying error is checked
+		err = urlErr.Err
+	}
+
+	var certErr *tls.CertificateVerificationError
+	var opErr *net.OpError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return true
+	case errors.Is(err, context.Canceled), errors.As(err, &certErr):
+		return false
+	case errors.As(err, &opErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// delay computes the delay before a given retry.
+func (r Retry) delay(attempt int) time.Duration {
+	d := r.Backoff
+	for i := 1; i < attempt && d < r.MaxBackoff; i++ {
+		d *= 2
+	}
+
+	return min(d, r.MaxBackoff)
+}
@@ -2,19 +2,23 @@ package service
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"path"
 	"regexp"
-	"strconv"
+	"sort"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/Jeffail/gabs"
 	"github.com/kingcobra2468/cot/internal/config"
+	"github.com/kingcobra2468/cot/internal/quote"
 )
 
 // Command input arg type.
@@ -26,9 +30,12 @@ type ArgDataType int8
 // Command output response type.
 type ResponseType int8
 
-// Mapping between the positional index of an argument of the input command and
-// its corresponding metadata.
-type ArgBindings map[int]*Arg
+// Command request body encoding.
+type BodyType int8
+
+// List of the arguments of an input command that belong to a given arg group, in the
+// order in which they were configured.
+type ArgBindings []*Arg
 
 // The set of supported HTTP methods when sending commands to a client service.
 type MethodSet map[string]struct{}
@@ -43,14 +50,33 @@ type UserInput struct {
 	Name string
 	Args []string
 	Raw  string
+	// Client number that sent the input command.
+	ClientNumber string
+	// Identifies the text that the command was sent in, as a single text can contain
+	// multiple commands.
+	Batch uint64
 }
 
 // Service handles the communication between a command request and the associated
 // client service.
 type Service struct {
 	Commands
-	Name    string
-	BaseURI string
+	Name        string
+	BaseURI     string
+	Description string
+	// Alternative names that the service can be referenced by.
+	Aliases []string
+	// Static headers that are sent with every command request.
+	Headers map[string]string
+	// Credentials that every command request is authenticated with, if any.
+	Auth *Auth
+	// When command requests to the service are stopped due to repeated failures.
+	Breaker Breaker
+	// Client that is shared by every command request so that its transport and
+	// connections are reused.
+	client *http.Client
+	// Circuit breaker that is shared by every command request, if any.
+	breaker *breaker
 }
 
 // Commands represents the global schematics of all commands for a given client service.
@@ -65,21 +91,73 @@ type Commands struct {
 // and where to perform the HTTP call, how to process the input command, as well
 // as how to process the client service output.
 type Command struct {
-	Method   string
-	Endpoint string
-	Response Response
-	Args     *ArgGroups
+	Method      string
+	Endpoint    string
+	Description string
+	Response    Response
+	Args        *ArgGroups
+	// Encoding of the request body, which determines whether the body is built from the
+	// JSON args or the form args.
+	BodyType BodyType
+	// Template that renders the request body from the body arguments instead of the
+	// body being built from the paths of the body arguments.
+	BodyTemplate *template.Template
+	// Content type of a raw request body.
+	ContentType string
+	// Compiled pattern of the command which is matched against the raw input command.
+	Pattern *regexp.Regexp
+	// Literal word from the pattern that identifies the command. Aliases of the command
+	// are rewritten into the keyword.
+	Keyword string
+	// Alternative words that the keyword of the command can be referenced by.
+	Aliases []string
+	// Static headers that are sent with every request of the command. These take
+	// precedence over the static headers of the service.
+	Headers map[string]string
+	// Time limit of each attempt of a request of the command.
+	Timeout time.Duration
+	// How failed requests of the command are retried.
+	Retry Retry
 }
 
 // Arg represents the metadata about a given input command argument.
 type Arg struct {
 	TypeInfo
 	Type ArgType
+	// Positional index of the argument within the input command. Only used when Name
+	// is not set.
+	Index int
+	// Name under which the argument is passed as a named arg (e.g. "--name=value" or
+	// "name:value").
+	Name string
+	// Named capture group of the command pattern from which the argument is extracted.
+	Group string
 	// Whether to compress the rest of the commands from the input command into an array
 	// under this argument.
-	Compress      bool
+	Compress bool
+	// Whether the compressed commands are joined by spaces into a single value instead.
+	Join          bool
 	Filter        []interface{}
 	FilterEnabled bool
+	// Name of the placeholder (e.g. "{name}") within the endpoint of the command that the
+	// argument fills. Endpoint arguments without a placeholder are appended to the endpoint.
+	Placeholder string
+	// Whether the input command must provide the argument. Optional arguments that
+	// are missing fall back to Default or are otherwise omitted from the request.
+	Required bool
+	// Raw default value of the argument which is cast like any other raw value.
+	Default *string
+	// Validation rules that each raw value of the argument must satisfy.
+	Rules []Rule
+	// Datatype of each of the values of a list argument.
+	ItemType ArgDataType
+	// Allowed values of an enum argument.
+	Values []string
+	// Layouts that a datetime argument is parsed with, and the location and layout that
+	// it is parsed and formatted in.
+	Layouts  []string
+	Location *time.Location
+	Format   string
 }
 
 // TypeInfo describes the metadata about a given argument and response attribute.
@@ -88,12 +166,56 @@ type TypeInfo struct {
 	Path     string
 }
 
+// Extract describes how the reply is extracted from a response and formatted based on
+// its datatype. Paths that begin with "$" are JSON path expressions, while other paths are
+// plain dot paths, XPath expressions, CSV paths or keys based on the response type.
+type Extract struct {
+	TypeInfo
+	// Datatype of each of the values of a list.
+	ItemType ArgDataType
+	// Compiled expression of the path, if the path is a JSON path expression.
+	Expr *JsonPath
+	// Labelled values that are extracted instead of the value at the path.
+	Fields []Field
+	// Number of decimal places that floats are formatted with, or -1 for as many as
+	// needed.
+	Precision int
+	// Words that bools are formatted as.
+	TrueWord  string
+	FalseWord string
+}
+
+// Field describes a labelled value that is extracted from a response.
+type Field struct {
+	TypeInfo
+	Label string
+	// Datatype of each of the values of a list.
+	ItemType ArgDataType
+	// Compiled expression of the path, if the path is a JSON path expression.
+	Expr *JsonPath
+}
+
 // Response describes how to process the output of the client service and address
 // cases of successful and erroneous output.
 type Response struct {
 	Type    ResponseType
-	Success TypeInfo
-	Error   TypeInfo
+	Success Extract
+	Error   Extract
+	// Template that renders the reply from the response instead of the reply being
+	// extracted from the success or error path.
+	Template *template.Template
+	// Replies for responses with a given status code, which take precedence over the
+	// replies for a given class of status codes (e.g. 4 for 4xx).
+	Replies      map[int]*Reply
+	ClassReplies map[int]*Reply
+}
+
+// Reply describes how the reply is produced from a response with a given status code. The
+// reply is either a static message, rendered from a template or extracted from the response.
+type Reply struct {
+	Extract
+	Template *template.Template
+	Message  string
 }
 
 // ArgType lists the different ways that the arguments of an input command can
@@ -108,6 +230,11 @@ const (
 	JsonArg
 	// EndpointArg describes an argument that will be used to construct the endpoint URL.
 	EndpointArg
+	// HeaderArg describes an argument that is designated for the HTTP headers.
+	HeaderArg
+	// FormArg describes an argument that is designated for a form-encoded or multipart
+	// body.
+	FormArg
 )
 
 // ResponseType lists the different ways in which the client service command output can
@@ -122,6 +249,32 @@ const (
 	// JsonResponse describes a response type where the output will be further processed
 	// through JSON.
 	JsonResponse
+	// XmlResponse describes a response type where the output will be further processed
+	// through XPath expressions.
+	XmlResponse
+	// CsvResponse describes a response type where the output will be further processed
+	// as CSV rows with a header.
+	CsvResponse
+	// KvResponse describes a response type where the output will be further processed
+	// as lines of "key=value" or "key: value" pairs.
+	KvResponse
+)
+
+// BodyType lists the different encodings in which the request body can be sent to the
+// client service.
+const (
+	// InvalidBody describes a body type that was specified for a given command but does
+	// not exist within cot.
+	InvalidBody BodyType = iota
+	// JsonBody describes a body that is encoded as JSON.
+	JsonBody
+	// FormBody describes a body that is encoded as application/x-www-form-urlencoded.
+	FormBody
+	// MultipartBody describes a body that is encoded as multipart/form-data.
+	MultipartBody
+	// RawBody describes a body that is rendered by the body template as is, with the
+	// content type of the command.
+	RawBody
 )
 
 // ArgDataType lists the different types of data types that are supported when casting
@@ -137,8 +290,25 @@ const (
 	FloatType
 	// BoolType describes that the cast will be made to the bool type.
 	BoolType
+	// DurationType describes that the cast will be made to a duration (e.g. "1h30m") in
+	// seconds.
+	DurationType
+	// DateTimeType describes that the cast will be made to a date and time which is then
+	// formatted into a timestamp.
+	DateTimeType
+	// EnumType describes that the cast will be made to one of a set of allowed values.
+	EnumType
+	// ListType describes that the cast will be made to a comma-separated list of values
+	// of another type.
+	ListType
+	// JsonType describes that the cast will be made to a raw JSON object.
+	JsonType
 )
 
+// placeholderPattern matches the placeholders (e.g. "{name}") within the endpoint of a
+// command.
+var placeholderPattern = regexp.MustCompile(`\{([^{}/]*)\}`)
+
 // supportedMethods describes the different HTTP methods that are supported by cot.
 var supportedMethods = MethodSet{
 	"get":    struct{}{},
@@ -153,7 +323,20 @@ var supportedMethods = MethodSet{
 func GenerateServices(c *config.Services) ([]Service, error) {
 	services := []Service{}
 	for _, s := range c.Services {
-		service := Service{Name: s.Name, BaseURI: s.BaseURI}
+		auth, err := generateAuth(s.Auth)
+		if err != nil {
+			return nil, fmt.Errorf("invalid auth for service \"%s\": %w", s.Name, err)
+		}
+		client, err := newClient(s.Transport, s.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transport for service \"%s\": %w", s.Name, err)
+		}
+		breaker, err := generateBreaker(s.Breaker)
+		if err != nil {
+			return nil, fmt.Errorf("invalid circuit breaker for service \"%s\": %w", s.Name, err)
+		}
+		service := Service{Name: s.Name, BaseURI: s.BaseURI, Description: s.Description, Aliases: lowerAll(s.Aliases),
+			Headers: s.Headers, Auth: auth, Breaker: breaker, client: client}
 		subCommands := Commands{}
 		subCommands.Meta = make(map[string]*Command)
 		subCommands.Patterns = []string{}
@@ -176,6 +359,9 @@ func GenerateServices(c *config.Services) ([]Service, error) {
 		}
 
 		service.Commands = subCommands
+		if err := service.checkCommandAliases(); err != nil {
+			return nil, err
+		}
 		services = append(services, service)
 	}
 
@@ -197,29 +383,71 @@ func generateSubCommand(cmdInfo *config.Command) (*Command, error) {
 	if len(cmdInfo.Pattern) == 0 {
 		cmdInfo.Pattern = ".*"
 	}
+	pattern, err := regexp.Compile(cmdInfo.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern \"%s\": %w", cmdInfo.Pattern, err)
+	}
+	if err := checkArgGroups(args, pattern); err != nil {
+		return nil, err
+	}
+	if err := checkPlaceholders(cmdInfo.Endpoint, args); err != nil {
+		return nil, err
+	}
+	bt, err := parseBodyType(cmdInfo.BodyType)
+	if err != nil {
+		return nil, err
+	}
+	if (bt == JsonBody || bt == RawBody) && len((*args)[FormArg]) > 0 {
+		return nil, errors.New("form args require a body type of \"form\" or \"multipart\"")
+	}
+	if bt != JsonBody && bt != RawBody && len((*args)[JsonArg]) > 0 {
+		return nil, errors.New("json args require a body type of \"json\" or \"raw\"")
+	}
+	contentType, err := generateContentType(cmdInfo.ContentType, bt, cmdInfo.BodyTemplate)
+	if err != nil {
+		return nil, err
+	}
 
-	sc := Command{Endpoint: cmdInfo.Endpoint, Method: cmdInfo.Method, Args: args}
+	tmpl, err := generateBodyTemplate(cmdInfo.BodyTemplate, bt, cmdInfo.Method, args)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := generateTimeout(cmdInfo.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	retry, err := generateRetry(cmdInfo.Retry, cmdInfo.Method)
+	if err != nil {
+		return nil, err
+	}
+
+	sc := Command{Endpoint: cmdInfo.Endpoint, Method: cmdInfo.Method, Description: cmdInfo.Description,
+		Args: args, Pattern: pattern, Aliases: lowerAll(cmdInfo.Aliases), Headers: cmdInfo.Headers, BodyType: bt,
+		BodyTemplate: tmpl, ContentType: contentType, Timeout: timeout, Retry: retry}
 	rt, err := parseResponseType(cmdInfo.Response.Type)
 	if err != nil {
 		return nil, err
 	}
 
 	sc.Response.Type = rt
-	if rt == PlainTextResponse {
+	if sc.Response.Template, err = generateResponseTemplate(cmdInfo.Response.Template); err != nil {
+		return nil, err
+	}
+	if sc.Response.Replies, sc.Response.ClassReplies, err = generateReplies(cmdInfo.Response.Responses, rt); err != nil {
+		return nil, err
+	}
+	// the response template replies to responses that are not replied to otherwise, so
+	// the success and error paths are never used
+	if rt == PlainTextResponse || sc.Response.Template != nil {
 		return &sc, nil
 	}
 
-	sdt, err := parseArgDataType(cmdInfo.Response.Success.DataType)
-	if err != nil {
+	if sc.Response.Success, err = generateExtract(cmdInfo.Response.Success, rt); err != nil {
 		return nil, err
 	}
-	sc.Response.Success = TypeInfo{DataType: sdt, Path: cmdInfo.Response.Success.Path}
-
-	edt, err := parseArgDataType(cmdInfo.Response.Error.DataType)
-	if err != nil {
+	if sc.Response.Error, err = generateExtract(cmdInfo.Response.Error, rt); err != nil {
 		return nil, err
 	}
-	sc.Response.Error = TypeInfo{DataType: edt, Path: cmdInfo.Response.Error.Path}
 
 	return &sc, nil
 }
@@ -228,10 +456,14 @@ func generateSubCommand(cmdInfo *config.Command) (*Command, error) {
 // file of a given command. The arguments are then preprocessed and aggregated into similar types.
 func generateArgs(argInfo *[]config.Arg, method string) (*ArgGroups, error) {
 	ag := make(ArgGroups)
-	ag[QueryArg] = make(ArgBindings)
-	ag[JsonArg] = make(ArgBindings)
-	ag[EndpointArg] = make(ArgBindings)
-	argCompress := false
+	ag[QueryArg] = ArgBindings{}
+	ag[JsonArg] = ArgBindings{}
+	ag[EndpointArg] = ArgBindings{}
+	ag[HeaderArg] = ArgBindings{}
+	ag[FormArg] = ArgBindings{}
+	// positional index of the compressed arg, if any, and the highest positional index
+	compressIndex, maxIndex := -1, -1
+	names := make(map[string]struct{})
 
 	for _, arg := range *argInfo {
 		filterEnabled := false
@@ -244,14 +476,49 @@ func generateArgs(argInfo *[]config.Arg, method string) (*ArgGroups, error) {
 		if err != nil {
 			return nil, err
 		}
-		if t == JsonArg && strings.EqualFold("get", method) {
+		if (t == JsonArg || t == FormArg) && strings.EqualFold("get", method) {
 			return nil, fmt.Errorf("arg index %d for path %s cannot exist for GET requests", arg.Index, arg.Path)
 		}
+		if t == HeaderArg && arg.Path == "" {
+			return nil, fmt.Errorf("arg index %d requires a path with the name of the header", arg.Index)
+		}
 
-		if argCompress && arg.CompressRest {
+		compress := arg.CompressRest || arg.JoinRest
+		name := strings.ToLower(arg.Name)
+		if arg.Group != "" {
+			if name != "" {
+				return nil, fmt.Errorf("arg \"%s\" cannot be bound to both a name and group \"%s\"", arg.Name, arg.Group)
+			}
+			if compress {
+				return nil, fmt.Errorf("arg for group \"%s\" cannot perform arg compression", arg.Group)
+			}
+		}
+		if name != "" {
+			if strings.ContainsAny(name, ":= ") || strings.HasPrefix(name, "-") {
+				return nil, fmt.Errorf("arg name \"%s\" cannot contain ':', '=', spaces or begin with '-'", arg.Name)
+			}
+			if _, exists := names[name]; exists {
+				return nil, fmt.Errorf("repeated arg name \"%s\" detected", arg.Name)
+			}
+			if compress {
+				return nil, fmt.Errorf("named arg \"%s\" cannot perform arg compression", arg.Name)
+			}
+			names[name] = struct{}{}
+		}
+
+		if compressIndex != -1 && compress {
 			return nil, errors.New("it is not possible to perform arg compression more than once on a single command")
-		} else if arg.CompressRest {
-			argCompress = true
+		} else if compress {
+			compressIndex = arg.Index
+		}
+		if arg.CompressRest && arg.JoinRest {
+			return nil, fmt.Errorf("arg index %d for path %s cannot both compress and join the rest of the args", arg.Index, arg.Path)
+		}
+		if arg.CompressRest && dt == ListType {
+			return nil, fmt.Errorf("arg index %d for path %s cannot compress the rest of the args into a list of lists", arg.Index, arg.Path)
+		}
+		if name == "" && arg.Group == "" {
+			maxIndex = max(maxIndex, arg.Index)
 		}
 
 		if arg.Filter != nil {
@@ -259,14 +526,92 @@ func generateArgs(argInfo *[]config.Arg, method string) (*ArgGroups, error) {
 			filterEnabled = true
 		}
 
-		// adds a given argument to a given arg group and points it to the positional
-		// index of the input command
-		ag[t][arg.Index] = &Arg{Type: t, Compress: arg.CompressRest, TypeInfo: TypeInfo{DataType: dt, Path: arg.Path}, Filter: filter, FilterEnabled: filterEnabled}
+		required := arg.Required == nil || *arg.Required
+		var def *string
+		if arg.Default != nil {
+			if arg.Required != nil && *arg.Required {
+				return nil, fmt.Errorf("arg index %d for path %s cannot be required while having a default value", arg.Index, arg.Path)
+			}
+			required = false
+			raw := formatConfigValue(arg.Default)
+			def = &raw
+		}
+
+		// adds a given argument to a given arg group and points it to either the positional
+		// index or the name under which it appears in the input command
+		a := &Arg{Type: t, Index: arg.Index, Name: name, Group: arg.Group, Compress: compress, Join: arg.JoinRest, Required: required, Default: def,
+			TypeInfo: TypeInfo{DataType: dt, Path: arg.Path}, Filter: filter, FilterEnabled: filterEnabled}
+		if err := a.setupDataType(arg); err != nil {
+			return nil, err
+		}
+		if a.Rules, err = generateRules(arg.Validate, a.elemType()); err != nil {
+			return nil, err
+		}
+		if def != nil {
+			if _, err := a.parse(*def); err != nil {
+				return nil, fmt.Errorf("invalid default value for %s: %w", a.label(), err)
+			}
+		}
+		ag[t] = append(ag[t], a)
+	}
+
+	// the compressed arg consumes all of the args that follow it
+	if compressIndex != -1 && maxIndex > compressIndex {
+		return nil, fmt.Errorf("arg index %d cannot follow the compressed arg at index %d", maxIndex, compressIndex)
 	}
 
 	return &ag, nil
 }
 
+// checkArgGroups validates that each of the args which are extracted from a named capture
+// group refer to a group that exists within the command pattern.
+func checkArgGroups(ag *ArgGroups, pattern *regexp.Regexp) error {
+	for _, group := range *ag {
+		for _, arg := range group {
+			if arg.Group != "" && pattern.SubexpIndex(arg.Group) == -1 {
+				return fmt.Errorf("group \"%s\" does not exist in pattern \"%s\"", arg.Group, pattern.String())
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkPlaceholders binds each of the placeholders within the endpoint to the endpoint arg
+// whose path, or otherwise name or group, matches the placeholder. Each placeholder must
+// be bound to exactly one arg which always has a value.
+func checkPlaceholders(endpoint string, ag *ArgGroups) error {
+	for _, match := range placeholderPattern.FindAllStringSubmatch(endpoint, -1) {
+		name := match[1]
+		if name == "" {
+			return fmt.Errorf("endpoint \"%s\" contains an empty placeholder", endpoint)
+		}
+		var bound *Arg
+		for _, arg := range (*ag)[EndpointArg] {
+			if arg.key() != name {
+				continue
+			}
+			if bound != nil {
+				return fmt.Errorf("endpoint placeholder \"%s\" is bound to more than one arg", name)
+			}
+			bound = arg
+		}
+
+		if bound == nil {
+			return fmt.Errorf("endpoint placeholder \"%s\" is not bound to any endpoint arg", name)
+		}
+		if bound.Placeholder != "" {
+			return fmt.Errorf("endpoint placeholder \"%s\" is repeated", name)
+		}
+		if !bound.Required && bound.Default == nil {
+			return fmt.Errorf("endpoint placeholder \"%s\" cannot be bound to an optional arg without a default value", name)
+		}
+		bound.Placeholder = name
+	}
+
+	return nil
+}
+
 // parseArgType processes the raw arg type from the configuration file into one of the
 // supported types.
 func parseArgType(t string) (ArgType, error) {
@@ -277,6 +622,10 @@ func parseArgType(t string) (ArgType, error) {
 		return JsonArg, nil
 	case "endpoint":
 		return EndpointArg, nil
+	case "header":
+		return HeaderArg, nil
+	case "form":
+		return FormArg, nil
 	default:
 		return InvalidArg, fmt.Errorf("invalid arg type detected \"%s\"", t)
 	}
@@ -293,7 +642,18 @@ func parseArgDataType(t string) (ArgDataType, error) {
 		return FloatType, nil
 	case "bool", "boolean":
 		return BoolType, nil
+	case "duration":
+		return DurationType, nil
+	case "datetime":
+		return DateTimeType, nil
+	case "enum":
+		return EnumType, nil
+	case "json":
+		return JsonType, nil
 	default:
+		if t == "list" || strings.HasPrefix(t, "list:") {
+			return ListType, nil
+		}
 		return InvalidType, fmt.Errorf("invalid arg datatype detected \"%s\"", t)
 	}
 }
@@ -306,11 +666,34 @@ func parseResponseType(t string) (ResponseType, error) {
 		return PlainTextResponse, nil
 	case "json":
 		return JsonResponse, nil
+	case "xml":
+		return XmlResponse, nil
+	case "csv":
+		return CsvResponse, nil
+	case "kv":
+		return KvResponse, nil
 	default:
 		return InvalidResponse, fmt.Errorf("invalid response type detected \"%s\"", t)
 	}
 }
 
+// parseBodyType processes the raw body type into one of the supported body types. If no
+// body type is specified, then the body is encoded as JSON.
+func parseBodyType(t string) (BodyType, error) {
+	switch t {
+	case "", "json":
+		return JsonBody, nil
+	case "form":
+		return FormBody, nil
+	case "multipart":
+		return MultipartBody, nil
+	case "raw":
+		return RawBody, nil
+	default:
+		return InvalidBody, fmt.Errorf("invalid body type detected \"%s\"", t)
+	}
+}
+
 // methodExists checks to see if the specified HTTP method is supported by cot.
 func methodExists(method string) bool {
 	method = strings.ToLower(method)
@@ -322,24 +705,44 @@ func methodExists(method string) bool {
 // Execute will push the command request to the associated client service and will
 // retrieve the output.
 func (s Service) Execute(ui *UserInput) (string, error) {
-	client := &http.Client{Timeout: time.Second * 10}
-	c, err := s.findSubCmd(ui)
+	client := s.client
+	if client == nil {
+		client = defaultClient
+	}
+	s.resolveAlias(ui)
+	c, groups, err := s.findSubCmd(ui)
 	if err != nil {
+		if suggestion, ok := s.suggestSubCmd(ui); ok {
+			return "", fmt.Errorf("unknown subcommand '%s' for '%s', did you mean '%s'?", ui.Args[0], s.Name, suggestion)
+		}
 		return "", err
 	}
 
-	req, err := s.setupRequest(c, ui)
+	req, args, err := s.setupRequest(c, ui, groups)
 	if err != nil {
 		return "", err
 	}
 
-	resp, err := client.Do(req)
+	// nothing may return between allowing the request and recording its outcome, as a
+	// half-open breaker would otherwise never close
+	if s.breaker != nil && !s.breaker.allow() {
+		return "", fmt.Errorf("service '%s' %w, try again later", s.Name, ErrUnavailable)
+	}
+	resp, cancel, err := s.send(client, c, req)
+	var body []byte
+	if err == nil {
+		body, err = s.readBody(c, resp, cancel)
+	}
+	// the outcome is recorded once the body has been read, as timing out while reading it
+	// is also a failure
+	if s.breaker != nil {
+		s.breaker.record(err != nil || resp.StatusCode >= http.StatusInternalServerError)
+	}
 	if err != nil {
 		return "", err
 	}
 
-	defer resp.Body.Close()
-	msg, err := s.processResponse(c, resp)
+	msg, err := s.processResponse(c, resp.StatusCode, body, args)
 	if err != nil {
 		return "", err
 	}
@@ -348,133 +751,308 @@ func (s Service) Execute(ui *UserInput) (string, error) {
 }
 
 // findSubCmd maps the input command into a client service command by doing
-// a check of the command pattern.
-func (sc Commands) findSubCmd(c *UserInput) (*Command, error) {
+// a check of the command pattern. The values of the named capture groups which
+// participated in the match are also returned.
+func (sc Commands) findSubCmd(c *UserInput) (*Command, map[string]string, error) {
 	for _, p := range sc.Patterns {
-		if found, err := regexp.MatchString(p, c.Raw); err == nil && found {
-			return sc.Meta[p], nil
+		cmd := sc.Meta[p]
+		pattern := cmd.Pattern
+		if pattern == nil {
+			var err error
+			if pattern, err = regexp.Compile(p); err != nil {
+				continue
+			}
 		}
-	}
 
-	return nil, errors.New("unable to find a valid subcommand from the input command")
-}
+		match := pattern.FindStringSubmatchIndex(c.Raw)
+		if match == nil {
+			continue
+		}
 
-// processResponse processes the client service command output based on the criteria
-// specified for the command.
-func (s Service) processResponse(c *Command, resp *http.Response) (string, error) {
-	bodyBytes, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
+		groups := make(map[string]string)
+		for i, name := range pattern.SubexpNames() {
+			// skip unnamed groups and groups which did not participate in the match
+			if name == "" || match[2*i] < 0 {
+				continue
+			}
+			// quotes are removed from the match like they are from the args
+			groups[name] = quote.Unquote(c.Raw[match[2*i]:match[2*i+1]])
+		}
 
-	if c.Response.Type == PlainTextResponse {
-		return string(bodyBytes), nil
+		return cmd, groups, nil
 	}
 
-	output, err := gabs.ParseJSON(bodyBytes)
+	return nil, nil, errors.New("unable to find a valid subcommand from the input command")
+}
+
+// readBody reads the response body of the command request and then releases the request
+// by calling its cancel function.
+func (s Service) readBody(c *Command, resp *http.Response, cancel context.CancelFunc) ([]byte, error) {
+	defer cancel()
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		// the timeout of the command also covers reading the body
+		return nil, s.timeoutError(c, err)
 	}
 
-	var respPath string
-	switch resp.StatusCode {
-	case http.StatusOK:
-		respPath = c.Response.Success.Path
-	default:
-		respPath = c.Response.Error.Path
-	}
-	msg := output.Path(respPath).String()
+	return body, nil
+}
 
-	return msg, nil
+// processResponse processes the client service command output based on the criteria
+// specified for the command and the status code of the response. The args are passed to
+// the response template, if any.
+func (s Service) processResponse(c *Command, status int, body []byte, args map[string]interface{}) (string, error) {
+	return c.Response.reply(status).render(c.Response.Type, body, status, args)
 }
 
 // setupRequest prepares for the client service command request by parsing the user command.
 // Preprocessing is then preformed to prepare the request based on the criteria specified for
-// the command.
-func (s Service) setupRequest(c *Command, ui *UserInput) (*http.Request, error) {
-	query, err := c.queryString(ui)
+// the command. The args that the response template is rendered with are also returned when
+// the command has a response template.
+func (s Service) setupRequest(c *Command, ui *UserInput, groups map[string]string) (*http.Request, map[string]interface{}, error) {
+	in, err := c.splitArgs(ui, groups)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	json, err := c.jsonString(ui)
+	query, err := c.queryString(in)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	endpoint, err := c.endpointString(ui)
+	endpoint, err := c.endpointString(in)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-
-	var serializedJson *bytes.Buffer = bytes.NewBuffer([]byte{})
-	// check if any json args exist for the given command
-	if len(json) > 0 {
-		serializedJson = bytes.NewBuffer([]byte(json))
+	body, contentType, err := c.body(in, &TemplateData{ClientNumber: ui.ClientNumber, Service: s.Name, Command: ui.Raw,
+		Method: strings.ToUpper(c.Method), Endpoint: endpoint})
+	if err != nil {
+		return nil, nil, err
+	}
+	headers, err := c.headers(in)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	req, err := http.NewRequest(strings.ToUpper(c.Method), s.BaseURI+path.Join(c.Endpoint, endpoint), serializedJson)
+	req, err := http.NewRequest(strings.ToUpper(c.Method), s.BaseURI+endpoint, body)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	req.URL.RawQuery = query
-	if len(json) != 0 {
-		req.Header.Add("Content-Type", "application/json")
+	if len(contentType) != 0 {
+		req.Header.Add("Content-Type", contentType)
 	}
 
 	switch c.Response.Type {
-	case PlainTextResponse:
+	case PlainTextResponse, KvResponse:
 		req.Header.Add("Accept", "text/plain")
 	case JsonResponse:
 		req.Header.Add("Accept", "application/json")
+	case XmlResponse:
+		req.Header.Add("Accept", "application/xml")
+	case CsvResponse:
+		req.Header.Add("Accept", "text/csv")
+	}
+
+	// static headers are applied from least to most specific, followed by the header args
+	for _, h := range []map[string]string{s.Headers, c.Headers} {
+		for key, val := range h {
+			req.Header.Set(key, val)
+		}
+	}
+	for key, vals := range headers {
+		req.Header[key] = vals
+	}
+	// credentials are applied last so that they cannot be overridden by the input command
+	if s.Auth != nil {
+		s.Auth.apply(req)
+	}
+
+	var args map[string]interface{}
+	if c.Response.Template != nil {
+		if args, err = c.templateArgs(in, responseArgTypes...); err != nil {
+			return nil, nil, err
+		}
 	}
 
-	return req, nil
+	return req, args, nil
 }
 
-// endpointString aggregates all of the endpoint arguments into the endpoint URL
-func (sc Command) endpointString(c *UserInput) (string, error) {
-	endpoint := []string{}
-	argCount := len((*sc.Args)[EndpointArg])
-	if argCount > len(c.Args) {
-		return "", errors.New("unable to parse input command due to invalid among of input args")
+// body serializes the body arguments from the input command based on the body type of the
+// command. If the command has a body template, then the body is rendered from the template
+// data instead. The content type of the body is also returned, which is empty if the command
+// has no body.
+func (sc Command) body(in *argInput, data *TemplateData) (*bytes.Buffer, string, error) {
+	if sc.BodyTemplate != nil {
+		args, err := sc.templateArgs(in, bodyArgType(sc.BodyType))
+		if err != nil {
+			return nil, "", err
+		}
+		data.Args = args
+		body, err := renderBody(sc.BodyTemplate, sc.BodyType, data)
+		if err != nil {
+			return nil, "", err
+		}
+		switch sc.BodyType {
+		case FormBody:
+			return body, "application/x-www-form-urlencoded", nil
+		case RawBody:
+			return body, sc.ContentType, nil
+		}
+		return body, "application/json", nil
 	}
 
-	if argCount == 0 {
-		return "", nil
+	switch sc.BodyType {
+	case FormBody, MultipartBody:
+		form, err := sc.formValues(in)
+		if err != nil || len(form) == 0 {
+			return bytes.NewBuffer([]byte{}), "", err
+		}
+		if sc.BodyType == FormBody {
+			return bytes.NewBufferString(form.Encode()), "application/x-www-form-urlencoded", nil
+		}
+		return multipartBody(form)
+	default:
+		json, err := sc.jsonString(in)
+		if err != nil || len(json) == 0 {
+			return bytes.NewBuffer([]byte{}), "", err
+		}
+		return bytes.NewBufferString(json), "application/json", nil
 	}
+}
 
-	for idx, arg := range (*sc.Args)[EndpointArg] {
-		if err := arg.check(c.Args[idx]); err != nil {
-			return "", err
+// multipartBody encodes the form values as multipart/form-data, with each value as a
+// separate field. The fields are written in the order of their keys.
+func multipartBody(form url.Values) (*bytes.Buffer, string, error) {
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+
+	keys := make([]string, 0, len(form))
+	for key := range form {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, val := range form[key] {
+			if err := w.WriteField(key, val); err != nil {
+				return nil, "", err
+			}
 		}
-		endpoint = append(endpoint, c.Args[idx])
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
 	}
 
-	return strings.Join(endpoint, "/"), nil
+	return body, w.FormDataContentType(), nil
 }
 
-// queryString aggregates all of the query arguments from the input command.
-func (sc Command) queryString(c *UserInput) (string, error) {
-	query := url.Values{}
-	argCount := len((*sc.Args)[QueryArg])
-	if argCount > len(c.Args) {
-		return "", errors.New("unable to parse input command due to invalid amount of input args")
+// formValues aggregates all of the form arguments from the input command. Each of the
+// values of a compressed arg is added as a separate form field under the same key.
+func (sc Command) formValues(in *argInput) (url.Values, error) {
+	form := url.Values{}
+	for _, arg := range (*sc.Args)[FormArg] {
+		raws, err := in.lookup(arg)
+		if err != nil {
+			return nil, err
+		}
+		for _, raw := range raws {
+			val, err := arg.parse(raw)
+			if err != nil {
+				return nil, err
+			}
+			if err := addQuery(form, arg.Path, val); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return form, nil
+}
+
+// headers aggregates all of the header arguments from the input command. Each of the values
+// of a compressed arg is added as a separate value of the same header.
+func (sc Command) headers(in *argInput) (http.Header, error) {
+	headers := http.Header{}
+	for _, arg := range (*sc.Args)[HeaderArg] {
+		raws, err := in.lookup(arg)
+		if err != nil {
+			return nil, err
+		}
+		for _, raw := range raws {
+			val, err := arg.parse(raw)
+			if err != nil {
+				return nil, err
+			}
+			s, err := format(val)
+			if err != nil {
+				return nil, err
+			}
+			headers.Add(arg.Path, s)
+		}
+	}
+
+	return headers, nil
+}
+
+// endpointString constructs the endpoint URL from the endpoint of the command. Endpoint
+// arguments that are bound to a placeholder fill the placeholder, while the rest of the
+// endpoint arguments are appended to the endpoint. Each of the values of a compressed arg
+// becomes a separate segment of the URL. Every value is escaped as a path segment.
+func (sc Command) endpointString(in *argInput) (string, error) {
+	endpoint := sc.Endpoint
+	segments := []string{}
+
+	for _, arg := range (*sc.Args)[EndpointArg] {
+		raws, err := in.lookup(arg)
+		if err != nil {
+			return "", err
+		}
+		values := []string{}
+		for _, raw := range raws {
+			val, err := arg.parse(raw)
+			if err != nil {
+				return "", err
+			}
+			segment, err := format(val)
+			if err != nil {
+				return "", err
+			}
+			// dot segments would otherwise be resolved when the URL is cleaned
+			if segment == "" || segment == "." || segment == ".." {
+				return "", fmt.Errorf("invalid value \"%s\" for %s within the endpoint", segment, arg.label())
+			}
+			values = append(values, url.PathEscape(segment))
+		}
+
+		if arg.Placeholder != "" {
+			endpoint = strings.ReplaceAll(endpoint, "{"+arg.Placeholder+"}", strings.Join(values, "/"))
+		} else {
+			segments = append(segments, values...)
+		}
 	}
 
-	if argCount == 0 {
+	return path.Join(append([]string{endpoint}, segments...)...), nil
+}
+
+// queryString aggregates all of the query arguments from the input command. Each of the
+// values of a compressed arg is added as a separate query param under the same key.
+func (sc Command) queryString(in *argInput) (string, error) {
+	query := url.Values{}
+	if len((*sc.Args)[QueryArg]) == 0 {
 		return "", nil
 	}
 
-	for idx, arg := range (*sc.Args)[QueryArg] {
-		if err := arg.check(c.Args[idx]); err != nil {
+	for _, arg := range (*sc.Args)[QueryArg] {
+		raws, err := in.lookup(arg)
+		if err != nil {
 			return "", err
 		}
-		query.Add(arg.Path, c.Args[idx])
-		if arg.Compress {
-			for i := idx + 1; i < len(c.Args)-1; i++ {
-				if err := arg.check(c.Args[i]); err != nil {
-					return "", err
-				}
-				query.Add(arg.Path, c.Args[i])
+		for _, raw := range raws {
+			val, err := arg.parse(raw)
+			if err != nil {
+				return "", err
+			}
+			if err := addQuery(query, arg.Path, val); err != nil {
+				return "", err
 			}
 		}
 	}
@@ -482,70 +1060,49 @@ func (sc Command) queryString(c *UserInput) (string, error) {
 	return query.Encode(), nil
 }
 
-// check will perform a lookup of a raw arg value against a filter list to see if it is
-// allowed.
-func (a Arg) check(ra string) error {
-	if !a.FilterEnabled {
-		return nil
+// addQuery adds a cast arg value to the query params or form values. Each value of a list
+// is added as a separate param under the same key.
+func addQuery(query url.Values, key string, val interface{}) error {
+	vals, ok := val.([]interface{})
+	if !ok {
+		vals = []interface{}{val}
 	}
-	for _, val := range a.Filter {
-		if val == ra {
-			return nil
+	for _, v := range vals {
+		s, err := format(v)
+		if err != nil {
+			return err
 		}
+		query.Add(key, s)
 	}
 
-	return fmt.Errorf("invalid or blacklisted value \"%s\" for arg", ra)
+	return nil
 }
 
-// jsonString aggregates all of the json body arguments from the input command.
-func (sc Command) jsonString(c *UserInput) (string, error) {
+// jsonString aggregates all of the json body arguments from the input command. The values
+// of a compressed arg are aggregated into an array.
+func (sc Command) jsonString(in *argInput) (string, error) {
 	json := gabs.New()
-	argCount := len((*sc.Args)[JsonArg])
-	if argCount > len(c.Args) {
-		return "", errors.New("unable to parse input command due to invalid among of input args")
-	}
-	if argCount == 0 {
+	if len((*sc.Args)[JsonArg]) == 0 {
 		return "", nil
 	}
 
-	for idx, arg := range (*sc.Args)[JsonArg] {
-		var val interface{}
-		var err error
-		if err := arg.check(c.Args[idx]); err != nil {
+	for _, arg := range (*sc.Args)[JsonArg] {
+		raws, err := in.lookup(arg)
+		if err != nil {
 			return "", err
 		}
-
-		if arg.Compress {
-			for i := idx; i < len(c.Args); i++ {
-				if err := arg.check(c.Args[i]); err != nil {
-					return "", err
-				}
-				json.ArrayAppendP(c.Args[i], arg.Path)
-			}
-			break
-		}
-
-		switch arg.DataType {
-		case StringType:
-			val = c.Args[idx]
-		case IntType:
-			val, err = strconv.ParseInt(c.Args[idx], 10, 64)
-			if err != nil {
-				return "", fmt.Errorf("unable to parse arg %s into an int", c.Args[idx])
-			}
-		case FloatType:
-			val, err = strconv.ParseFloat(c.Args[idx], 64)
+		for _, raw := range raws {
+			val, err := arg.parse(raw)
 			if err != nil {
-				return "", fmt.Errorf("unable to parse arg %s into an float", c.Args[idx])
+				return "", err
 			}
-		case BoolType:
-			val, err = strconv.ParseBool(c.Args[idx])
-			if err != nil {
-				return "", fmt.Errorf("unable to parse arg %s into an boolean", c.Args[idx])
+
+			if arg.Compress && !arg.Join {
+				json.ArrayAppendP(val, arg.Path)
+			} else {
+				json.SetP(val, arg.Path)
 			}
 		}
-
-		json.SetP(val, arg.Path)
 	}
 
 	return json.String(), nil
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"regexp/syntax"
+	"strings"
+	"unicode"
+)
+
+// Suggest finds the candidate that is closest to the input in terms of edit distance.
+// A candidate is only suggested if it is within a third of the length of the input
+// of edits away (with at least a single edit allowed), as any further away is
+// unlikely to be a typo.
+func Suggest(input string, candidates []string) (string, bool) {
+	input = strings.ToLower(input)
+	maxDistance := max(1, len([]rune(input))/3)
+	best, bestDistance := "", maxDistance+1
+
+	for _, c := range candidates {
+		d := editDistance(input, strings.ToLower(c))
+		if d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+
+	return best, bestDistance <= maxDistance
+}
+
+// editDistance computes the optimal string alignment distance between two strings. This is
+// the Levenshtein distance with the addition of transpositions of adjacent characters, which
+// are a common typo on phone keyboards.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(ra)][len(rb)]
+}
+
+// suggestSubCmd finds the subcommand keyword that is closest to the first arg of the
+// input command. Each command pattern contributes its keyword as a candidate.
+func (s Service) suggestSubCmd(ui *UserInput) (string, bool) {
+	if len(ui.Args) == 0 {
+		return "", false
+	}
+
+	candidates := []string{}
+	for _, p := range s.Patterns {
+		keyword := s.patternKeyword(p)
+		if keyword == "" {
+			continue
+		}
+		// an exact match means that the typo lies elsewhere in the input command
+		if strings.EqualFold(keyword, ui.Args[0]) {
+			return "", false
+		}
+		candidates = append(candidates, keyword)
+	}
+
+	return Suggest(ui.Args[0], candidates)
+}
+
+// patternKeyword finds the keyword of a command pattern, which is its first literal word
+// other than the name or an alias of the service. Patterns without such a word have no
+// keyword.
+func (s Service) patternKeyword(pattern string) string {
+	for _, word := range patternKeywords(pattern) {
+		if !strings.EqualFold(word, s.Name) && !containsFold(s.Aliases, word) {
+			return strings.ToLower(word)
+		}
+	}
+
+	return ""
+}
+
+// patternKeywords extracts the literal words from a command pattern.
+func patternKeywords(pattern string) []string {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil
+	}
+
+	words := []string{}
+	var walk func(re *syntax.Regexp)
+	walk = func(re *syntax.Regexp) {
+		if re.Op == syntax.OpLiteral {
+			words = append(words, strings.FieldsFunc(string(re.Rune), isNotWordRune)...)
+			return
+		}
+		for _, sub := range re.Sub {
+			walk(sub)
+		}
+	}
+	walk(re)
+
+	return words
+}
+
+// isNotWordRune checks whether a rune separates the words of a pattern.
+func isNotWordRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
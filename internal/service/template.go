@@ -0,0 +1,251 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+	"text/template"
+)
+
+// templateFuncs are the helper functions that are available within body and response
+// templates.
+var templateFuncs = template.FuncMap{
+	// json serializes a value into JSON, which quotes and escapes strings.
+	"json": func(val interface{}) (string, error) {
+		b, err := json.Marshal(val)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	},
+	// xml escapes a value for use within the text or attributes of an XML body.
+	"xml": func(val interface{}) (string, error) {
+		var b strings.Builder
+		if err := xml.EscapeText(&b, []byte(fmt.Sprint(val))); err != nil {
+			return "", err
+		}
+		return b.String(), nil
+	},
+	"number":   formatNumber,
+	"date":     formatDate,
+	"ago":      formatAgo,
+	"truncate": truncate,
+	// query evaluates a JSON path expression against a value (e.g. the response body).
+	"query": func(expr string, doc interface{}) (interface{}, error) {
+		p, err := compileJsonPath(expr)
+		if err != nil {
+			return nil, err
+		}
+		return p.Select(doc), nil
+	},
+}
+
+// defaultContentType is the content type of a raw body when none is specified.
+const defaultContentType = "text/plain"
+
+// responseArgTypes are the arg types whose args are passed to response templates, in
+// the order in which args with the same key override each other.
+var responseArgTypes = []ArgType{EndpointArg, QueryArg, HeaderArg, FormArg, JsonArg}
+
+// TemplateData is the data that a body template is rendered with.
+type TemplateData struct {
+	// Mapping between the key (path, name or group) of each of the body args and its
+	// typed value. Optional args that were not provided are nil.
+	Args map[string]interface{}
+	// Client number that sent the input command.
+	ClientNumber string
+	// Name of the service.
+	Service string
+	// Raw input command.
+	Command string
+	// HTTP method and endpoint of the request.
+	Method   string
+	Endpoint string
+}
+
+// generateBodyTemplate parses and validates the body template of a command from the
+// configuration file. The template is validated by rendering it with the zero value of
+// each of the body args, which for a JSON body must render into valid JSON.
+func generateBodyTemplate(text string, bt BodyType, method string, ag *ArgGroups) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	if bt == MultipartBody {
+		return nil, errors.New("body template cannot be used with a body type of \"multipart\"")
+	}
+	if strings.EqualFold("get", method) {
+		return nil, errors.New("body template cannot exist for GET requests")
+	}
+
+	tmpl, err := template.New("body").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid body template: %w", err)
+	}
+
+	data := TemplateData{Args: make(map[string]interface{})}
+	for _, arg := range (*ag)[bodyArgType(bt)] {
+		data.Args[arg.key()] = zeroValue(arg.DataType)
+	}
+	if _, err := renderBody(tmpl, bt, &data); err != nil {
+		return nil, fmt.Errorf("invalid body template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+// generateContentType validates the content type of a command from the configuration file.
+// Only raw bodies, which are rendered by a body template, have a configurable content type,
+// which defaults to "text/plain".
+func generateContentType(contentType string, bt BodyType, text string) (string, error) {
+	if bt != RawBody {
+		if contentType != "" {
+			return "", errors.New("content type requires a body type of \"raw\"")
+		}
+		return "", nil
+	}
+	if text == "" {
+		return "", errors.New("body type of \"raw\" requires a body template")
+	}
+	if contentType == "" {
+		return defaultContentType, nil
+	}
+	if _, _, err := mime.ParseMediaType(contentType); err != nil {
+		return "", fmt.Errorf("invalid content type \"%s\"", contentType)
+	}
+
+	return contentType, nil
+}
+
+// ResponseData is the data that a response template is rendered with.
+type ResponseData struct {
+	// Parsed response body for JSON, CSV and key-value responses, or the raw response
+	// body otherwise.
+	Body interface{}
+	// Response status code.
+	Status int
+	// Mapping between the key (path, name or group) of each of the args and its typed
+	// value. Optional args that were not provided are nil.
+	Args map[string]interface{}
+}
+
+// generateResponseTemplate parses the response template of a command from the
+// configuration file.
+func generateResponseTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+
+	tmpl, err := template.New("response").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+// renderResponse renders the response template with the response body, which is parsed
+// based on the response type, the response status code and the args. An empty body is
+// rendered as nil unless the response is plain text.
+func renderResponse(tmpl *template.Template, rt ResponseType, body []byte, status int, args map[string]interface{}) (string, error) {
+	data := ResponseData{Body: string(body), Status: status, Args: args}
+	if rt != PlainTextResponse {
+		data.Body = nil
+		if len(bytes.TrimSpace(body)) > 0 {
+			doc, err := parseDocument(rt, body)
+			if err != nil {
+				return "", err
+			}
+			data.Body = doc.data()
+		}
+	}
+
+	var msg strings.Builder
+	if err := tmpl.Execute(&msg, data); err != nil {
+		return "", fmt.Errorf("unable to render response: %w", err)
+	}
+
+	return strings.TrimSpace(msg.String()), nil
+}
+
+// renderBody renders the body template with the template data. A JSON body must render
+// into valid JSON, while a raw body is rendered as is.
+func renderBody(tmpl *template.Template, bt BodyType, data *TemplateData) (*bytes.Buffer, error) {
+	body := &bytes.Buffer{}
+	if err := tmpl.Execute(body, data); err != nil {
+		return nil, err
+	}
+	if bt == JsonBody && !json.Valid(body.Bytes()) {
+		return nil, errors.New("body template did not render into valid JSON")
+	}
+
+	return body, nil
+}
+
+// templateArgs fetches the typed values of each of the args of the given arg types from
+// the input command keyed by the key of the arg. The values of a compressed arg are
+// aggregated into an array unless they are joined.
+func (sc Command) templateArgs(in *argInput, types ...ArgType) (map[string]interface{}, error) {
+	args := make(map[string]interface{})
+	bindings := ArgBindings{}
+	for _, t := range types {
+		bindings = append(bindings, (*sc.Args)[t]...)
+	}
+
+	for _, arg := range bindings {
+		raws, err := in.lookup(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		vals := []interface{}{}
+		for _, raw := range raws {
+			val, err := arg.parse(raw)
+			if err != nil {
+				return nil, err
+			}
+			vals = append(vals, val)
+		}
+
+		switch {
+		case arg.Compress && !arg.Join:
+			args[arg.key()] = vals
+		case len(vals) > 0:
+			args[arg.key()] = vals[0]
+		default:
+			args[arg.key()] = nil
+		}
+	}
+
+	return args, nil
+}
+
+// bodyArgType fetches the arg type that builds the body of a given body type.
+func bodyArgType(bt BodyType) ArgType {
+	if bt == FormBody || bt == MultipartBody {
+		return FormArg
+	}
+
+	return JsonArg
+}
+
+// zeroValue fetches the zero value of the typed value of a given datatype.
+func zeroValue(dt ArgDataType) interface{} {
+	switch dt {
+	case IntType:
+		return int64(0)
+	case FloatType, DurationType:
+		return float64(0)
+	case BoolType:
+		return false
+	case ListType:
+		return []interface{}{}
+	case JsonType:
+		return map[string]interface{}{}
+	default:
+		return ""
+	}
+}
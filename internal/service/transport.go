@@ -0,0 +1,133 @@
+package service
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/kingcobra2468/cot/internal/config"
+)
+
+// defaultTimeout is the time limit of a request to a client service when the command
+// does not specify one.
+const defaultTimeout = time.Second * 10
+
+// defaultMaxIdleConns is the number of idle connections to a client service that are
+// kept for reuse when none is specified.
+const defaultMaxIdleConns = 4
+
+// noProxy is the proxy setting that disables proxying, including proxies from the
+// environment.
+const noProxy = "direct"
+
+// defaultClient is the client used by services which were not set up with their own.
+var defaultClient = &http.Client{}
+
+// tlsVersions maps the supported minimum TLS versions to their identifiers.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// newClient creates the client that is reused across all of the requests to a client
+// service, so that its connections can be reused. The client has no timeout of its own
+// as each request is limited by the timeout of its command.
+func newClient(transportInfo *config.Transport, tlsInfo *config.TLS) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = defaultMaxIdleConns
+	if transportInfo != nil {
+		if err := setupTransport(transport, transportInfo); err != nil {
+			return nil, err
+		}
+	}
+	if tlsInfo != nil {
+		tlsConfig, err := generateTLSConfig(tlsInfo)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
+
+// setupTransport validates and applies the connection pooling and proxy settings of a
+// service from the configuration file to its transport. By default, the proxy is read
+// from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+func setupTransport(transport *http.Transport, transportInfo *config.Transport) error {
+	if transportInfo.MaxIdleConns < 0 || transportInfo.MaxConns < 0 {
+		return errors.New("transport connection limits cannot be negative")
+	}
+	if transportInfo.MaxIdleConns > 0 {
+		transport.MaxIdleConnsPerHost = transportInfo.MaxIdleConns
+	}
+	transport.MaxConnsPerHost = transportInfo.MaxConns
+
+	if transportInfo.IdleTimeout != "" {
+		timeout, err := time.ParseDuration(transportInfo.IdleTimeout)
+		if err != nil || timeout < 0 {
+			return fmt.Errorf("invalid transport idle timeout \"%s\"", transportInfo.IdleTimeout)
+		}
+		transport.IdleConnTimeout = timeout
+	}
+
+	switch transportInfo.Proxy {
+	case "":
+	case noProxy:
+		transport.Proxy = nil
+	default:
+		proxy, err := url.Parse(transportInfo.Proxy)
+		if err != nil || proxy.Scheme == "" || proxy.Host == "" {
+			return fmt.Errorf("invalid transport proxy \"%s\"", transportInfo.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	return nil
+}
+
+// generateTLSConfig parses and validates the TLS config of a service from the configuration
+// file. When a CA bundle is specified, only the certificates within it are trusted.
+func generateTLSConfig(tlsInfo *config.TLS) (*tls.Config, error) {
+	tlsConfig := &tls.Config{ServerName: tlsInfo.ServerName, MinVersion: tls.VersionTLS12}
+
+	if tlsInfo.MinVersion != "" {
+		version, ok := tlsVersions[tlsInfo.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("invalid tls min version detected \"%s\"", tlsInfo.MinVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+
+	if tlsInfo.CAFile != "" {
+		pem, err := os.ReadFile(tlsInfo.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read tls ca file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found within tls ca file \"%s\"", tlsInfo.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if (tlsInfo.CertFile == "") != (tlsInfo.KeyFile == "") {
+		return nil, errors.New("tls client certificate requires both a cert file and a key file")
+	}
+	if tlsInfo.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(tlsInfo.CertFile, tlsInfo.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load tls client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
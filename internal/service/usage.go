@@ -0,0 +1,227 @@
+package service
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"sort"
+	"strings"
+)
+
+// Usage renders the usage of each of the commands of a service, one command per line.
+// Each line contains the readable form of the pattern of the command followed by its args
+// and description. Positional args are rendered as "<index:datatype>", named args as
+// "<--name:datatype>" and args from named capture groups as "<group:datatype>" in place of
+// their group within the pattern. Optional args are wrapped in square brackets instead.
+func (s Service) Usage() string {
+	lines := []string{s.Summary()}
+	for _, p := range s.Patterns {
+		c, ok := s.Meta[p]
+		if !ok {
+			continue
+		}
+
+		line := []string{s.Name}
+		if pattern := c.readablePattern(p, s.Name); pattern != "" {
+			line = append(line, pattern)
+		}
+		if len(c.Aliases) > 0 {
+			line = append(line, "("+strings.Join(c.Aliases, ", ")+")")
+		}
+		line = append(line, c.usageArgs()...)
+		if c.Description != "" {
+			line = append(line, "-", c.Description)
+		}
+		lines = append(lines, strings.Join(line, " "))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Summary renders the name of a service along with its aliases and description if they exist.
+func (s Service) Summary() string {
+	summary := s.Name
+	if len(s.Aliases) > 0 {
+		summary += " (" + strings.Join(s.Aliases, ", ") + ")"
+	}
+	if s.Description == "" {
+		return summary
+	}
+
+	return fmt.Sprintf("%s - %s", summary, s.Description)
+}
+
+// usageArgs renders each of the args of a command other than the args from named capture
+// groups. Positional args are rendered first in order of their index, followed by named
+// args in order of their name.
+func (sc Command) usageArgs() []string {
+	args := []*Arg{}
+	if sc.Args != nil {
+		for _, group := range *sc.Args {
+			for _, arg := range group {
+				// args from named capture groups are rendered within the pattern
+				if arg.Group == "" {
+					args = append(args, arg)
+				}
+			}
+		}
+	}
+	sort.SliceStable(args, func(i, j int) bool {
+		if args[i].positional() != args[j].positional() {
+			return args[i].positional()
+		}
+		if args[i].positional() && args[i].Index != args[j].Index {
+			return args[i].Index < args[j].Index
+		}
+		// args are collected from a map, so args with the same index or name are ordered
+		// by their path and arg type for the usage to be stable
+		if args[i].Name != args[j].Name {
+			return args[i].Name < args[j].Name
+		}
+		if args[i].Path != args[j].Path {
+			return args[i].Path < args[j].Path
+		}
+
+		return args[i].Type < args[j].Type
+	})
+
+	usage := make([]string, 0, len(args))
+	for _, arg := range args {
+		usage = append(usage, arg.usage())
+	}
+
+	return usage
+}
+
+// readablePattern renders a pattern of a command as the words that it matches, with each
+// named capture group replaced by the usage of its arg (e.g. "^car set (?P<model>\\w+)$"
+// is rendered as "set <model:str>"). The leading service name is omitted, while other
+// parts of the pattern, such as character classes and repetitions, only separate words.
+// Patterns that cannot be parsed are rendered as they are.
+func (sc Command) readablePattern(p, name string) string {
+	re, err := syntax.Parse(p, syntax.Perl)
+	if err != nil {
+		return p
+	}
+	groups := make(map[string]*Arg)
+	if sc.Args != nil {
+		for _, group := range *sc.Args {
+			for _, arg := range group {
+				if arg.Group != "" {
+					groups[arg.Group] = arg
+				}
+			}
+		}
+	}
+
+	words := strings.Fields(readableRegexp(re, groups))
+	if len(words) > 0 && strings.EqualFold(words[0], name) {
+		words = words[1:]
+	}
+
+	return strings.Join(words, " ")
+}
+
+// readableRegexp renders a parsed pattern as the words that it matches.
+func readableRegexp(re *syntax.Regexp, groups map[string]*Arg) string {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return string(re.Rune)
+	case syntax.OpCapture:
+		if re.Name == "" {
+			return readableRegexp(re.Sub[0], groups)
+		}
+		if arg, ok := groups[re.Name]; ok {
+			return " " + arg.usage() + " "
+		}
+		return " <" + re.Name + "> "
+	case syntax.OpConcat:
+		var b strings.Builder
+		for _, sub := range re.Sub {
+			b.WriteString(readableRegexp(sub, groups))
+		}
+		return b.String()
+	case syntax.OpAlternate:
+		alts := make([]string, 0, len(re.Sub))
+		for _, sub := range re.Sub {
+			alts = append(alts, strings.Join(strings.Fields(readableRegexp(sub, groups)), " "))
+		}
+		return " (" + strings.Join(alts, "|") + ") "
+	case syntax.OpQuest:
+		if s := strings.Join(strings.Fields(readableRegexp(re.Sub[0], groups)), " "); s != "" {
+			return " [" + s + "] "
+		}
+	}
+
+	return " "
+}
+
+// usage renders an arg along with its datatype and constraints.
+func (a Arg) usage() string {
+	var b strings.Builder
+	switch {
+	case a.Group != "":
+		b.WriteString(a.Group)
+	case a.Name != "":
+		b.WriteString("--" + a.Name)
+	default:
+		b.WriteString(fmt.Sprint(a.Index))
+	}
+	switch a.DataType {
+	case ListType:
+		b.WriteString(":list:" + a.ItemType.String())
+	case EnumType:
+		b.WriteString(":" + strings.Join(a.Values, "|"))
+	default:
+		b.WriteString(":" + a.DataType.String())
+	}
+	if a.Compress {
+		b.WriteString("...")
+	}
+	if a.Default != nil {
+		b.WriteString("=" + *a.Default)
+	}
+	if a.FilterEnabled {
+		filter := make([]string, 0, len(a.Filter))
+		for _, val := range a.Filter {
+			filter = append(filter, fmt.Sprint(val))
+		}
+		b.WriteString(" in " + strings.Join(filter, "|"))
+	}
+
+	if a.Required {
+		return "<" + b.String() + ">"
+	}
+
+	return "[" + b.String() + "]"
+}
+
+// positional checks whether an arg is referenced by its positional index.
+func (a Arg) positional() bool {
+	return a.Name == "" && a.Group == ""
+}
+
+// String fetches the name of a datatype as it is specified in the configuration file.
+func (dt ArgDataType) String() string {
+	switch dt {
+	case StringType:
+		return "str"
+	case IntType:
+		return "int"
+	case FloatType:
+		return "float"
+	case BoolType:
+		return "bool"
+	case DurationType:
+		return "duration"
+	case DateTimeType:
+		return "datetime"
+	case EnumType:
+		return "enum"
+	case ListType:
+		return "list"
+	case JsonType:
+		return "json"
+	default:
+		return "invalid"
+	}
+}
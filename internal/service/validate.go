@@ -0,0 +1,151 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kingcobra2468/cot/internal/config"
+)
+
+// Rule represents a set of validation constraints that a raw arg value must satisfy.
+// Constraints that are not set are not enforced.
+type Rule struct {
+	Regex     *regexp.Regexp
+	Min       *float64
+	Max       *float64
+	MinLength *int
+	MaxLength *int
+	// Allowed values of the arg.
+	Enum []string
+	// Disallowed values of the arg.
+	Deny []string
+	// Whether the enum and deny values are compared while ignoring case.
+	CaseInsensitive bool
+	// Datatype that the enum and deny values are compared as.
+	DataType ArgDataType
+	// Custom error message for when any of the constraints are not satisfied.
+	Message string
+}
+
+// generateRules parses and validates the validation rules of an arg from the configuration
+// file.
+func generateRules(ruleInfo []config.Rule, dt ArgDataType) ([]Rule, error) {
+	rules := []Rule{}
+	for _, r := range ruleInfo {
+		rule := Rule{Min: r.Min, Max: r.Max, MinLength: r.MinLength, MaxLength: r.MaxLength,
+			Enum: stringify(r.Enum), Deny: stringify(r.Deny), CaseInsensitive: r.CaseInsensitive, Message: r.Message,
+			DataType: dt}
+
+		if r.Regex != "" {
+			re, err := regexp.Compile(r.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("invalid validation regex \"%s\": %w", r.Regex, err)
+			}
+			rule.Regex = re
+		}
+		if (r.Min != nil || r.Max != nil) && dt != IntType && dt != FloatType {
+			return nil, errors.New("min and max validation can only be performed on int or float args")
+		}
+		if r.Min != nil && r.Max != nil && *r.Min > *r.Max {
+			return nil, fmt.Errorf("validation min %v is greater than max %v", *r.Min, *r.Max)
+		}
+		if r.MinLength != nil && r.MaxLength != nil && *r.MinLength > *r.MaxLength {
+			return nil, fmt.Errorf("validation min length %d is greater than max length %d", *r.MinLength, *r.MaxLength)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// check will perform a lookup of a raw arg value against a filter list to see if it is
+// allowed. The raw arg value is then validated against each of the validation rules.
+func (a Arg) check(ra string) error {
+	if a.FilterEnabled && !matches(stringify(a.Filter), ra, a.elemType(), false) {
+		return fmt.Errorf("invalid or blacklisted value \"%s\" for arg", ra)
+	}
+
+	for _, r := range a.Rules {
+		if err := r.validate(ra); err != nil {
+			if r.Message != "" {
+				return errors.New(r.Message)
+			}
+			return fmt.Errorf("invalid value \"%s\" for %s: %w", ra, a.label(), err)
+		}
+	}
+
+	return nil
+}
+
+// validate checks whether a raw arg value satisfies each of the constraints of the rule.
+func (r Rule) validate(ra string) error {
+	if r.Regex != nil && !r.Regex.MatchString(ra) {
+		return fmt.Errorf("must match \"%s\"", r.Regex.String())
+	}
+
+	if r.Min != nil || r.Max != nil {
+		val, err := strconv.ParseFloat(ra, 64)
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		if r.Min != nil && val < *r.Min {
+			return fmt.Errorf("must be at least %v", *r.Min)
+		}
+		if r.Max != nil && val > *r.Max {
+			return fmt.Errorf("must be at most %v", *r.Max)
+		}
+	}
+
+	length := utf8.RuneCountInString(ra)
+	if r.MinLength != nil && length < *r.MinLength {
+		return fmt.Errorf("must be at least %d characters", *r.MinLength)
+	}
+	if r.MaxLength != nil && length > *r.MaxLength {
+		return fmt.Errorf("must be at most %d characters", *r.MaxLength)
+	}
+
+	if len(r.Enum) > 0 && !matches(r.Enum, ra, r.DataType, r.CaseInsensitive) {
+		return fmt.Errorf("must be one of %s", strings.Join(r.Enum, ", "))
+	}
+	if matches(r.Deny, ra, r.DataType, r.CaseInsensitive) {
+		return errors.New("is not allowed")
+	}
+
+	return nil
+}
+
+// matches checks whether a raw arg value is within a list of values. The values of int and
+// float args are compared as numbers (e.g. "1.5" matches "1.50"), while other values are
+// compared as strings.
+func matches(values []string, ra string, dt ArgDataType, caseInsensitive bool) bool {
+	num, err := strconv.ParseFloat(ra, 64)
+	numeric := err == nil && (dt == IntType || dt == FloatType)
+	for _, val := range values {
+		if numeric {
+			if v, err := strconv.ParseFloat(val, 64); err == nil && v == num {
+				return true
+			}
+		}
+		if val == ra || (caseInsensitive && strings.EqualFold(val, ra)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stringify converts each of the values from the configuration file into its string form
+// so that it can be compared against raw arg values.
+func stringify(values []interface{}) []string {
+	strs := make([]string, 0, len(values))
+	for _, val := range values {
+		strs = append(strs, formatConfigValue(val))
+	}
+
+	return strs
+}